@@ -111,7 +111,7 @@ func assetsFaviconIco() (*asset, error) {
 	return a, nil
 }
 
-var _assetsIndexGohtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x84\x54\xd1\x8a\xeb\x36\x10\x7d\x0f\xe4\x1f\xa6\xa6\x60\xa7\x34\x12\xa5\xf4\x25\x6b\xe7\x96\x7b\x69\xa1\x85\x96\xd2\xdb\x87\x42\xd3\x07\xad\x34\x8e\x95\x95\x25\x23\x8d\x9d\x98\xac\xff\xbd\x28\x76\xb2\x49\x37\x7b\x77\x1e\x82\x46\x73\x72\x34\x73\x38\x9e\xbc\xa2\xda\xac\xe7\xb3\xf9\x2c\xaf\x50\xa8\xf5\x7c\x06\x00\x90\xd7\x48\x02\xac\xa8\xb1\x48\x3a\x8d\xfb\xc6\x79\x4a\x40\x3a\x4b\x68\xa9\x48\xf6\x5a\x51\x55\x28\xec\xb4\xc4\xe5\x29\xf9\x16\xb4\xd5\xa4\x85\x59\x06\x29\x0c\x16\xdf\x25\x67\xa6\x40\xbd\xc1\x29\x39\x1e\xd9\xe7\x98\x0e\xc3\x54\xe4\xd7\xd5\x9c\x34\x19\x5c\x1f\x8f\xec\x67\x6d\xf0\x77\x51\xe3\x30\xe4\x7c\xbc\x9c\x10\x46\xdb\x27\xf0\x68\x8a\x34\x54\xce\x93\x6c\x09\xb4\x74\x36\x05\xea\x1b\x2c\x52\x5d\x8b\x2d\xf2\xc3\x72\xbc\xab\x3c\x96\x45\xca\x4b\xd1\xc5\x9c\x69\xe9\x52\xe0\xeb\xf9\x2c\xe7\xd3\xa4\xf3\x59\xfe\xe8\x54\x7f\x26\x57\xba\x03\x69\x44\x08\x45\x52\xab\x65\x1c\x56\x68\x8b\x3e\x01\xad\x8a\xe4\x2a\x3d\xf5\x5c\x24\x4a\x87\xc6\x88\x7e\x05\xd6\x59\x7c\x38\xcf\xfb\x8a\x49\xf8\x27\xe5\xf6\x76\x19\x5f\xba\x06\x4d\x7a\x7c\x74\xaa\x3f\xcb\x31\x4a\xa2\x74\x77\xee\xe8\xfa\x1c\xa4\xd7\x0d\x5d\x11\x70\x0e\x9f\x75\xdd\x18\x04\xc2\x40\x90\x49\xd7\x7a\xc2\xd0\x83\x2b\x81\x2a\x84\xdf\x04\x55\xbf\x8a\x03\x34\xde\xed\x50\x52\xbc\xab\x03\x9a\x0e\xc3\x02\xa8\x12\x04\xb2\x42\xf9\x14\xa0\x74\x1e\x1a\xb1\xc5\x1b\x66\x6d\xa5\x69\x15\x06\xf8\x0b\xff\x86\x47\x2c\x9d\x47\xd0\x75\xb4\x81\xb6\x5b\xd0\xf4\x02\xce\xca\xd6\x4a\xd2\xce\x42\xb6\x80\xe3\xed\x7c\x9d\xf0\x10\xe7\x86\x02\x94\x93\x6d\x8d\x96\x58\xcc\x19\xe1\x81\x3e\x8d\x6e\x7a\xb8\xfd\x8b\x2e\x21\x3b\x41\x6a\x41\xb2\xca\x78\xf6\x61\xb5\xf9\xfa\x79\xb3\xd9\x64\xf1\xe7\x9f\xe7\xcd\xe6\x11\xb7\xda\x6e\x8e\xec\x9b\x0f\xc3\xbf\x0b\xbe\x78\xf5\xe8\x99\xe5\xab\xbd\xb6\xca\xed\xd9\xa4\xc3\x5d\x5c\x8c\x5b\x18\x14\x6f\xe1\x62\x04\x12\x9e\xda\x66\xf5\x25\x4c\x8c\xa8\xe7\x9f\x28\x54\xff\x5a\x93\x7b\x71\x11\x67\x8b\xf4\x93\xc1\x78\xfc\xd8\xff\xa2\xb2\xf4\xe2\xba\x74\xc1\x4e\xb6\x63\x93\xeb\xa0\x80\x34\x7d\x78\x9f\xd9\x23\xb5\xde\x9e\xbd\xc0\xa6\xfe\x99\xc2\x52\xb4\x86\xfe\x78\x69\xf3\x1d\xae\xe1\xed\xf2\x1b\xa5\xe1\x0e\xe3\x1d\x68\xb4\xc8\x68\xed\x6b\x93\x48\x8f\x82\x70\x92\x22\x4b\x47\x40\x7a\xaf\xc9\xb1\xc4\x82\x97\x51\x92\x8a\xa8\x09\x2b\xce\xa5\xb2\x6c\x17\x14\x1a\xdd\x79\x66\x91\xb8\x6d\x6a\x5e\x0b\xaa\x76\xe2\xf0\xe3\xf7\x1c\xc3\x0f\x9c\xf0\xb0\xac\x6b\xb3\x94\x71\x01\xb2\x5d\xb8\xa7\xe6\xa5\x9f\xb8\x30\x98\x68\x1a\xb4\xea\x53\xa5\x8d\xca\xc6\x67\xff\xdf\xd0\xd5\x7c\xc3\xe2\xa2\x69\xce\x2f\xdf\x6e\xce\xa7\x85\x73\xda\x42\x71\xf3\xfe\x17\x00\x00\xff\xff\xa7\x61\xcf\xe0\x80\x05\x00\x00")
+var _assetsIndexGohtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcd\x5a\x5b\x73\xdb\xc6\x15\x7e\xf7\xaf\x58\x23\x33\x01\x38\x21\x40\xdb\xb1\x93\x0c\x45\xaa\xf5\x2d\xad\x3b\x8a\xa5\x46\xca\x93\x27\xed\xac\x80\x25\xb1\x16\x6e\x01\x96\xa4\x18\x85\x7d\xcc\x73\xa7\x3f\x31\xbf\xa4\xdf\xd9\x05\x48\x00\x04\x28\x2a\x69\xd3\x72\x86\x37\x60\xf7\x9c\xb3\xdf\xb9\xef\x62\x12\xaa\x38\x3a\x7d\xf4\x68\x12\x0a\x1e\x9c\x3e\x62\x78\x4d\x62\xa1\x38\x4b\x78\x2c\xa6\xd6\x52\x8a\x55\x96\xe6\xca\x62\x7e\x9a\x28\x91\xa8\xa9\xb5\x92\x81\x0a\xa7\x81\x58\x4a\x5f\xb8\xfa\xcf\x90\xc9\x44\x2a\xc9\x23\xb7\xf0\x79\x24\xa6\x4f\xad\x92\x50\xa1\xd6\x91\x30\xbf\xef\xee\xbc\x4b\xfa\xb7\xd9\x98\x5b\xa3\xda\x3d\x33\x8e\xc5\x22\x90\x7c\x6a\x65\xb9\x4c\x54\x49\x81\x5e\x7f\xcc\xf8\x5c\xb0\x3b\x56\xc8\x1f\xc5\x98\xbd\x7c\x7e\xc2\x62\x9e\xcf\x65\x32\x66\xcf\xfc\xf8\x84\x6d\xb6\x03\x3f\x21\x11\xf3\x34\x2a\x86\xec\x93\x84\x2f\xf1\xa9\x52\x1f\x9f\xd7\xdc\xbf\x89\x64\x72\x43\xd7\x89\x56\x0e\x62\x81\x2c\xb2\x88\xaf\xc7\x2c\x49\x13\xc1\x1e\xcb\x98\x16\xc9\x13\x55\xa7\xe7\xc5\x81\x4b\x24\xb9\x4c\x9a\x73\xae\xa3\xd4\xbf\x81\x18\x32\x71\x43\x21\xe7\xa1\x1a\xb3\x27\xcd\x89\x3c\xbf\x09\xd2\x55\xe2\x5e\xa7\xc1\x1a\x33\x35\x4a\x63\xc6\x17\x2a\x3d\x61\x19\x0f\x02\x99\xcc\x0f\xcf\x39\x65\xe1\x53\xf6\x0f\xfa\xb8\x63\xd7\xb9\xe0\x37\xee\xb5\x98\xa5\x39\x00\xa0\x15\xd4\x27\x86\x4f\x87\x2c\x7c\x86\xf7\xe7\x78\x3f\xc7\xfb\x05\xde\x5f\x6c\xa7\xf1\x99\x12\x39\x58\x2f\x53\x19\xd4\xa7\x65\xb9\x18\x9a\x85\xfc\xb0\x48\x15\x7e\x2b\x7e\x1d\xe1\x6b\x26\xe7\x0b\xba\x25\xe3\xf9\x90\x15\xcb\xf9\x96\x90\x4c\x0a\x19\x88\x0e\x4a\xfc\x43\x98\x8b\xd9\xdf\xa6\x56\xa8\x54\x66\x7d\x3f\x1e\x6b\x96\x98\x57\x5a\xcc\x98\x59\xcc\xb1\x18\x57\x2a\x77\x68\xe4\x80\x59\x03\xeb\x84\xcd\x70\xd7\x35\x3a\x7d\xe2\x7d\x25\x1a\x9a\xf4\x66\x69\xaa\x12\x88\x55\x80\x4c\x73\xe0\x0b\x1a\xd9\x44\xa4\x21\x51\xd3\xb0\x94\x54\xf8\x0d\xdb\xbb\xa2\x1f\x9b\xcd\x64\x64\xae\x98\xbb\x64\x14\x2c\x17\xd1\xd4\x2e\x42\xa8\xdf\x5f\x28\x26\x21\xb4\xcd\xd4\x3a\x13\x53\x5b\xc6\x80\x7a\x74\xeb\x9a\x6b\x24\xfa\xd4\x1e\xcd\xf8\x92\xfe\x7b\xf8\xb0\xd9\xe8\xf4\xd1\x64\x64\x1c\xe7\xd1\x84\xf4\x56\xd9\xba\x9c\xc1\xb0\x14\xf3\x2e\xc8\x96\x2b\x93\x0f\xe4\x92\xc9\x60\x6a\x55\x66\x0a\x9f\x8a\x78\x51\x4c\xad\x99\xbc\x15\x01\x53\x69\xe6\x3e\x61\x39\xd9\x13\xbe\x63\xf7\x39\x9b\x45\xe2\x96\x49\x25\xe2\xc2\xf5\x01\xa4\xc8\x6b\x5e\xa1\x79\x78\x97\x82\xe7\x7e\xb8\xd9\x21\x37\x01\x24\x31\xe3\xbe\x92\x69\x32\xb5\x46\x85\xbe\x6f\xc1\xb5\x54\x98\x82\xf5\x5c\xa8\x2d\xd7\x38\x77\x9f\x59\x0c\x82\xc0\xcd\xcb\x71\x3b\xf2\x9a\x96\x4c\x32\x40\xa2\xc1\xa8\x46\x94\x61\xe1\x07\x8b\x2d\x79\xb4\xc0\x2f\x60\xfb\xd7\x85\xc8\xd7\x9b\x8d\xc5\xe0\x1d\xbe\x08\xd3\x28\x10\xf9\xd4\xba\x2c\x27\xf0\x5c\x72\x37\xe2\xd7\x80\xb9\xba\xd6\xe0\x42\xaf\x52\xa2\xec\xd6\xfd\x9c\x65\x6b\xf7\x29\xa4\x5a\x24\x01\x30\xb9\x4e\x73\x10\x63\xd7\x73\x57\xe5\x3c\x29\x32\x9e\x03\x07\x96\x66\xdc\x97\x6a\xed\x7e\xf9\xa2\x26\xf1\x64\x44\x4b\xaf\x03\x24\x92\xa0\x86\x8c\x06\x8c\x27\x01\x81\x96\x2f\x41\xdb\xbb\xe0\xaa\x01\x1d\x37\x3a\xb6\x46\xe2\x96\xa2\x81\x97\x05\xb3\x3f\xcc\x24\xe0\xc1\x1a\xcd\xd8\x06\x76\xac\x57\xdc\x9a\x7c\x4c\xdb\xdb\xd4\x7a\x03\xcf\x8e\x52\x1e\x30\x5e\xb0\x8b\x37\x5f\x5b\xa7\xf8\x98\x8c\xf8\x3d\xe2\x7a\xaf\x8d\xa9\xd4\xa5\xbc\x5e\x28\x95\x26\x75\x43\x72\x55\x3a\x9f\x47\xe2\xd7\x0b\x57\x72\x61\x2a\x14\xac\x20\x70\x60\x68\x1a\xa4\x7c\x32\x32\xec\xee\x93\xd3\x40\xda\x23\x26\xc8\xc6\xa2\x2d\xe4\x43\xe4\xbb\xd2\x53\x59\x80\x08\xc9\xe2\x34\x10\x2d\x3b\x35\x22\xbc\xc1\xdd\xcd\xe6\x8c\xdc\x07\x32\x46\x05\xbc\x9d\x2e\xb5\xe5\x3d\xbc\xa2\xc9\x08\x4e\x5a\x73\xe2\x96\x02\xda\x2e\xec\x66\x3c\x11\x51\xcb\x8f\xeb\x1e\x9c\xe1\xdd\x5a\x9f\x12\xb7\x08\x67\xf1\xce\x09\x74\xbc\x9a\x5a\x8d\x8c\x74\x42\xd1\x60\xcc\x3e\xcf\x75\xb4\x43\xfe\x9a\x6b\x2a\x63\x24\xda\x50\xe4\x52\x51\x0e\xbc\xdd\x26\x9f\x2f\x9f\x2c\xc3\x13\x96\x42\x5f\xb3\x28\x5d\x99\x3c\x53\xf7\x8c\x52\x17\x01\x57\xdc\xad\x42\x03\x81\x79\xaf\x32\xac\xd3\x0a\x7a\x31\xe3\x8b\x48\xed\x54\xb0\x0f\x63\x27\x17\x04\x57\x18\xfd\x11\x7c\xbe\xd5\x03\x29\x65\xe8\x04\xc4\x69\x7a\x07\x0f\x1d\xdd\xea\x0a\x20\xff\xdc\xe1\xbf\x17\x2f\x2b\x70\x4d\xc5\xe0\x6a\x50\x9f\x78\x2f\x00\x6b\x77\xac\x33\xd1\xcd\x10\x6d\x44\xb3\x37\x25\x00\xe6\x56\x3d\xa6\x35\xee\xf4\x45\xb6\xc3\x2e\xd9\x0c\x6f\x6d\xc9\x4a\x5c\xef\x85\xf0\x52\xa8\x0e\xc4\x5a\x51\x71\x92\x35\xe0\x2b\x14\x57\x8b\x5d\x22\xaa\x07\xd6\xc9\x28\xab\x4d\x5b\x44\x7b\xb0\xef\xa6\x45\xb2\x50\x2e\x99\x2d\xcd\x5a\x44\xa7\xfb\xae\xb4\xf3\xb0\x86\xb7\x91\x3b\x55\x08\xd5\x6a\x2d\x6b\xcb\xcb\xfc\x3d\xed\x08\xe2\xef\xf9\x92\x39\xbb\x1c\x3b\x68\x04\x72\xaa\x54\x34\x0d\x94\x81\x5b\x29\x43\x19\x04\x22\x61\xd1\x7c\xac\xcb\x1e\xb6\x72\xbf\x30\x29\xd6\x2d\x42\x90\xb8\x81\xcb\x92\xbb\x16\x4a\xfa\x37\xeb\x32\x1b\x17\x22\x9a\x11\x48\xb9\x32\x0e\x87\x02\x37\x17\x20\x52\xf9\x9a\x4b\xae\xb6\x75\xe9\x96\xe2\xc0\xbc\x61\x29\x17\x28\x27\x0a\x0b\xd5\x08\x2c\x14\xc6\xa5\x04\x33\xf2\xd1\x5a\xa8\x34\xc1\x9f\xba\xe2\xf4\x2a\x8e\xc9\x67\x57\xe7\xaf\x8f\x80\x02\xb5\xf0\xff\x0c\x0a\x2a\xc0\xce\x5f\x37\xa2\xf0\x3d\xab\x6b\x98\x46\xbd\x32\xee\x8c\xfd\x65\x99\x55\xb1\x69\x13\x2b\x45\x78\x85\xe9\x7b\x57\x69\xfa\xab\xaa\x41\x68\xdd\xad\xa1\xb7\xed\x21\x76\x59\x56\xb9\x5f\xb1\x4c\x01\x26\xe3\x80\xae\xea\x59\xbc\xa6\x14\x3e\x3b\x3d\xc3\x74\xb8\xeb\x2c\x4f\x63\x14\x8d\xcf\x3a\x06\xc1\xc9\xf6\x3d\x6a\x6f\x98\x91\x1b\xf1\x02\x5d\x51\xaf\xe8\x5b\x9a\x91\xdc\x46\x0e\x04\x0d\x38\xe8\xb6\xca\xd9\x55\x35\x8d\x02\x99\xc3\x87\x23\xd9\xc7\x76\x1f\x58\xa3\xce\xca\xeb\x7b\x15\xdc\x37\x7d\x6b\xc6\x4e\x9a\x93\x22\xc5\x12\xfe\x00\x1c\x07\x7d\x26\xbd\x75\x2d\x52\x8b\x6e\xe7\x0e\xa9\x44\xa7\x84\x8f\x0b\x58\xf2\x6c\x8d\x86\x41\xad\xc8\x64\x2b\x3d\x35\xbd\x13\xac\x65\xba\x28\xb4\x30\x09\x46\xe8\x4e\xab\xe8\x50\xc0\xdd\xdd\x4a\xaa\xd0\x08\x0b\xc4\x3a\x00\xd5\x4d\x05\x1a\x59\xb1\xb4\x4e\x7f\xf9\xf9\x9f\xac\x89\x6f\x55\x9e\x4c\x10\xf0\x13\xa0\xad\xbf\xfa\xa0\xad\x98\x11\x26\x87\x98\x91\xc4\x75\x45\xb2\x5f\x7e\xfe\x57\xc9\x6c\x8f\x6e\x2b\xd4\x74\xfa\xdf\x2e\x7a\xb7\x6b\xa2\x7a\x0d\xdd\xa1\xa3\x09\xe2\x82\xcc\x14\x95\x31\xbe\xe9\x0f\xde\xd3\x2f\x32\xb3\x2d\xf5\xd1\x88\x5d\x51\x51\xc8\x4c\x51\x38\xae\x55\x9e\x58\x4d\x2c\xe2\x6b\x91\x17\xfa\xa2\x1f\xa6\xd2\x87\x0b\x26\x8c\xa3\x38\x48\x6f\xa4\xd8\x12\x09\x52\x7f\x11\x23\x5f\x7a\x68\x6a\xde\x46\x82\x7e\xbe\x5a\xbf\x0b\x1c\xbb\x5e\x6f\xda\x03\x0f\xed\xf6\xdb\x25\x6e\x9e\xc1\xa7\x04\xd2\x89\x63\xfb\x11\xe2\x9a\x8d\x5e\x77\x91\xe8\x3a\x85\x39\x03\x76\xd7\x80\x63\xc9\x73\xa8\x3f\xe7\x71\xc1\xa6\x30\x86\x15\xfb\xee\xdb\x33\xd3\xbc\x5c\xe8\xab\xce\x4a\x26\x08\x49\x1e\xa2\xa7\x2e\x55\x3c\xd3\x1e\x0d\x4e\x1a\x54\x0c\x05\xdc\x53\xa5\x50\xe0\x59\x2f\x56\xed\x88\xea\x37\xbb\xb2\x07\x9b\xaa\x2b\xbb\xd4\x44\x8b\x54\x37\x3f\x08\x57\xf2\x50\xe9\xa5\x82\x16\xe6\x4e\x6d\x5e\x45\x03\x16\xa6\x55\xd2\x95\x8c\x2b\x7d\x56\x2d\xc0\xaf\x57\x68\x45\x41\x97\xc4\x63\xe6\xf3\x28\x32\x2a\x7c\x79\xf1\x8e\x69\x1b\xa6\x3f\x2a\xbd\x81\x03\xea\x2d\x1e\x2a\x5d\xd6\x35\xcd\x0f\xd9\x8d\x00\x1b\xd4\x2b\xb8\x28\x0b\x34\xf9\xe9\x0a\x77\x70\xb7\x28\xb0\xe4\x2d\x2f\xe7\x3e\xb5\x81\x3f\x80\xe9\x35\x8f\x46\xed\x6e\xb7\x70\xde\xd2\x26\xf9\x1d\xd3\x36\x0f\x41\x93\x36\xb9\x28\xfd\xb4\x39\x56\x5c\xcd\xba\xa6\x95\xb0\x97\x2a\xcd\x11\x3d\x88\xf7\x3b\x64\x7b\xc7\x8e\x83\xc2\xd5\x63\xec\x01\xfb\xe9\xa7\x4a\x9d\x19\x72\x41\x06\xe3\x20\x88\xf4\xdd\x21\xf5\x87\x35\x74\x30\x8d\x71\xc5\x74\xda\x5d\x64\x6d\x61\xe9\x05\x05\x3a\x8f\xf5\xdc\x2e\xd1\xe8\x95\x0b\xb5\xc8\x13\x76\x01\x5e\xb2\x10\x5e\x2e\x3e\x0a\x5f\x39\x64\xd4\x6f\xf3\x3c\x85\x3b\xbc\x4c\xd8\x56\x00\x06\xe0\x13\x21\x50\x57\xda\x83\x0e\x6e\xfb\xf1\xa9\xa4\x3e\x13\xca\x0f\x1d\x7b\xf4\x77\x48\x3c\xe2\x99\xb4\xd9\x67\x25\x6a\xdd\x42\x19\x64\xc7\xac\x42\x98\xb0\x1d\xeb\x4f\xf6\xe9\xa7\xec\x2f\x97\xe7\xef\xbd\x42\x1b\x34\xe2\xb6\xa3\x81\x1f\x76\xd2\xa1\xcd\x17\xc4\x8a\x31\xbb\x63\xf6\xcb\x05\x68\xe5\xf2\x47\xed\x21\xf6\x98\xd9\xaf\xe0\x24\x30\x20\x92\xa5\x44\xd7\x7e\x6d\xf6\xa4\xdc\xab\x75\x26\x68\x08\xcf\x32\x04\x03\x3d\x63\xf4\xb1\xa0\x7d\x9e\xfd\x25\x6e\x06\x1e\xac\x34\xa9\xd9\x5d\x2e\x8a\x3e\xb4\x49\x1f\xb8\xed\x99\xca\x9a\x4d\xa7\x53\xf6\xfc\xc9\xd3\xbe\xd1\xba\xff\x6b\x9a\x0c\xe2\x1f\x4a\xaa\x3d\xab\x39\xe9\x9c\xbf\x61\x14\x3a\x8e\x27\x5e\xec\xdb\xe3\xd0\x60\xd3\xc7\xe0\x90\x49\xd1\x3a\x09\x34\x67\x0f\x20\x6a\x02\x0f\xad\x59\x5b\x2d\x4d\x4f\x6f\x0e\x0d\xa3\x97\x0a\x11\x07\xd8\xce\x5c\x89\xb4\x27\xe8\x67\x8f\xc8\xfd\x62\xd7\x44\x27\x2a\x3d\x2b\xee\x32\xfb\xd6\xb5\x4d\x77\xcc\x28\xc2\x74\xe5\x28\x5d\xbc\xec\xaf\xe9\xde\x78\x64\x2c\x06\x09\x8b\x28\x94\x76\x8a\x88\x42\xff\x8e\xe3\xae\xe7\x3b\x9d\x96\xa9\x25\xb3\xeb\xad\xaa\xf6\x8a\xc2\xd3\x3f\x3f\x63\xb0\x02\xfa\xef\x14\x9e\xce\x53\x14\xa2\xf0\x5b\x77\xfb\x7f\x60\x26\x2f\x31\x78\x8b\xc9\x57\x83\x01\xcd\x60\x26\xa3\x1d\x87\x0c\xd5\xb3\xbd\x46\x41\xf1\x53\x37\x94\xc7\x44\x6d\x3d\xb0\xcb\x1d\xf4\x0d\x4f\x26\xc8\xf0\x7f\xbe\xfa\xe6\x0c\xb4\x6c\x7b\x7f\x94\xb6\x1e\x33\x14\x89\xe6\x2d\x47\xd0\xda\x59\xed\xac\xcf\x16\x49\x42\xda\x57\xa8\x0b\x88\xee\x07\x2d\x5c\x29\xa3\x03\x68\xec\xc1\x90\xe9\x1d\xe5\xfe\x41\xbc\xcf\x8f\x69\x9e\x47\x95\x1d\x89\x3d\x22\x55\xcc\xbc\x02\x91\x09\x73\x46\xb0\x88\x98\x67\x8e\x48\xfc\x34\x10\xdf\x7d\xfb\xee\x35\x72\x06\x3a\x03\x24\x68\xef\x63\x2a\x13\x3d\xe2\x00\xd5\xa6\x35\xcd\xba\x47\xd2\xe2\x3c\xc4\x42\x94\x06\xaf\x43\x19\x05\x0e\x4d\xed\xa1\x6a\xd0\xab\x0f\xa6\xd9\x0f\xf6\x9a\x7b\x35\xfd\x9b\x0a\xb8\x2a\xc8\xe8\x24\xef\xe9\x5d\x20\xaf\xdc\x61\x63\x8f\x11\x93\x6d\x6a\xae\xec\x3e\x7d\x77\xcd\xaa\xe6\x9c\x1c\x88\x88\xc7\x24\xcc\x1e\xda\xba\x11\xef\x20\xae\xeb\x10\xfb\x4f\x6f\xaf\xc8\x45\xed\x32\xd0\x1a\x57\xdf\x8b\xba\xf7\x54\x00\x0d\x52\xa3\x5e\x47\xaa\xd2\x1d\xf9\xec\xc0\x43\x6e\x6c\xf8\x88\xe8\x63\xa2\x43\x8c\xf0\x62\x24\x1c\x64\x9a\x63\xcc\x61\xaf\x5a\x26\x60\x7e\xa0\xc3\x84\x4b\x11\xa1\x46\xa1\xda\xe4\x43\x7d\x3b\x91\xe2\xd0\xf7\xbf\xc1\x20\x0c\x00\x17\xe7\x97\x06\x01\x1d\xd6\x5a\x88\xfe\x3f\x2d\xd7\x6c\x9e\xfe\x07\x17\x6c\x08\xda\xfb\xe5\xcc\xc1\x45\xda\x2f\xa9\x46\x12\x28\xd6\x74\x8a\xc8\x3d\x6e\xfe\x97\xd1\x07\x84\x75\x49\x0b\xef\x40\xd9\x9e\xcc\x6d\x9d\x1d\x3c\xf6\x1e\x55\x24\xfe\xa2\x6f\x43\x9e\xa7\xfa\x55\xcf\xef\xcd\xcb\xa0\x5b\x8e\x3b\x4c\xb7\xc7\x62\x7f\x3f\xbd\x1d\x95\xa1\x3a\x75\x56\x2c\xae\x63\xa9\x1a\x4a\x13\x34\xa2\x4b\x54\x7d\xc3\xa3\x1d\x04\x7c\x97\x79\xdb\x19\x9c\xdc\xa7\x61\xcd\x7b\x48\x87\xa6\xd2\xb4\xd5\xb2\xf0\x84\x11\xd2\xe4\x7a\x4f\x1f\xdb\x6d\x7d\xfc\xf7\x35\xfb\xcd\xc0\x79\x40\x4b\xfa\x8d\xc8\x63\x2e\x0f\xee\x31\x14\xb9\x3f\xb5\x4c\xcf\x11\x9b\xd1\xa8\x47\xad\xae\x4e\xb5\xc9\xf0\xc8\x96\xb6\x92\x20\x90\x7c\xae\xf7\x02\x48\x92\x20\xe7\xab\xa4\xdd\xb9\xd2\xa6\x9e\xbe\x40\x0d\x7a\xc4\xfc\x34\x5b\xb3\x74\x66\x86\x68\x56\x8f\x76\x8d\x8f\x91\xb3\x7c\x62\x42\xfe\x28\x9c\x3b\xd3\xdf\x9d\x27\x67\xf0\x4e\x28\x2d\x5f\x88\x21\x28\xfb\x8b\x5c\xaa\xf5\x19\x2c\x00\xed\xb4\x4d\xdd\x90\x4f\xc6\xa3\xcb\xae\x71\x73\x1f\xa1\xda\x38\x28\xb7\x11\x8c\xb9\x54\x3b\x09\xc7\xee\x03\x78\x67\x72\x29\xcc\x39\xcc\xc3\x3a\x7f\x9a\xc7\x4c\x6c\x19\xb3\x62\xc5\x33\xda\xae\xa1\xa5\xcf\xe0\xd1\x61\xb4\xc6\xbd\x04\x4d\x9a\x3e\xaa\x40\x7f\xb7\x82\xe1\x09\xda\xe9\xd1\x43\xa8\xfe\x44\xc7\xcf\x93\xb5\xf6\x72\x54\x22\xba\x6c\x29\xd0\x95\x30\x3f\xa4\x4d\xce\xe2\xd8\xce\xbf\x48\x17\xb9\x2f\xca\x0d\x1b\xed\x7b\x97\xfa\x4a\xd5\x96\x6a\x5f\x2a\xcc\x91\x2e\x85\xb2\xfd\x8a\xca\xd9\xee\xaa\xb5\x9b\x5f\x43\xbb\x2b\x10\x6b\x19\xef\x8d\xc4\xa6\x3f\x6e\xef\xe2\x50\xc5\x47\xde\xea\xa3\x0e\x85\x4e\x11\xea\xd0\x05\xa4\xb9\xb0\x1f\xd6\x73\xd6\xda\x31\xaa\xf6\x9c\x03\x49\x7d\x47\xaf\xaf\x4f\xa9\xd0\x44\x9c\x2b\xa1\x7c\x73\xfe\xcd\x05\xcf\x61\xea\xe8\xf3\x32\xfa\xf1\x35\xac\xbd\xdc\x6e\x22\x2a\x08\x3c\xf4\x35\xa2\x27\x89\xfa\x4a\x51\xaa\xc5\x40\xb1\x9d\xef\x8c\x81\x7d\xe8\xf6\x63\x64\x3e\xda\x0c\x78\x5c\xa2\x56\xde\x39\xd4\x2f\x9a\x7d\x45\x18\x55\x5e\xa8\xb6\xf7\x56\xfe\xa8\x9f\x26\xa2\x2d\x8e\xa2\xcb\x3d\xdb\xaf\xb6\xc6\x8c\x99\x3b\x07\x5a\xcf\xbe\x4a\xb0\xbf\x2d\xfd\x60\x37\x1f\xfe\xa1\x48\x4e\x0f\x30\xe9\x6f\x95\xfa\xf6\xf7\x1d\xbd\x4a\x51\x62\x78\x08\x0e\xdd\x58\x91\x0f\x9a\x96\xa4\x05\xfe\x96\xc2\x90\x21\xd4\xe4\xa6\x3f\xd8\x66\xb7\x9e\xb1\x27\x87\x5b\x7a\xc3\x8d\x0a\xec\xc7\x25\xcd\xfb\xda\xfb\x87\x03\x5c\xee\x79\x10\x3f\xcd\xee\x3e\x0e\xa5\x20\x8d\xd6\x50\x4f\xdc\x5d\x79\xe8\x46\xc2\xe6\x80\x95\x1f\x6f\xad\x95\xa9\xe7\x8b\x04\x39\xa0\x01\x38\x62\x41\xeb\x81\x30\x94\x02\x15\x4d\xbb\x97\xff\xe6\x98\xd2\xa8\x4f\xa4\xe3\x35\xf1\x5b\x72\xfc\x64\x64\x9e\x93\xa2\x07\xa7\xf4\x93\x87\x77\x77\xc8\x57\x32\x29\x0f\x43\x37\x9b\x03\xa7\x60\xdb\x53\xaf\x2a\x3f\xb5\x0f\xb7\xda\x4f\xcf\x50\x77\x0a\xdd\x37\xce\x15\x85\xe2\x32\x2a\xcc\x80\xf3\x8c\x6e\xb2\x14\x5f\xa5\x7c\xad\x53\xac\x62\x11\x43\x0d\xeb\xd3\xf2\x98\x51\xa7\x86\xae\x33\x98\x92\x9f\xb1\x34\x50\xac\x1e\x48\xa0\x47\xd7\xae\xd3\x28\x28\x8f\x9a\x4a\x53\x34\xe7\x56\x56\xc5\xb2\xfb\x74\x68\x77\xb5\x1c\x07\x3c\x4b\x69\x9a\x32\xd6\x4e\x4a\xb3\xc8\x7d\xde\x75\xc0\xbc\x03\xa2\x76\x98\x53\x9e\xed\x18\x3c\x1a\x27\xb1\xa5\x7b\xf5\x3f\x20\xf5\x5f\x59\x78\x4b\x82\x3a\x63\x3a\x21\xdb\x23\xdd\x78\x0c\xac\x4e\x4a\x9f\xa7\xf5\x3c\xde\x53\x9d\x6c\xee\xcc\x71\x11\x6d\xcf\xc8\xfe\x0d\x0f\x54\x9d\xda\x0c\x2b\x00\x00")
 
 func assetsIndexGohtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -126,7 +126,7 @@ func assetsIndexGohtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "assets/index.gohtml", size: 1408, mode: os.FileMode(438), modTime: time.Unix(1600717202, 0)}
+	info := bindataFileInfo{name: "assets/index.gohtml", size: 11020, mode: os.FileMode(438), modTime: time.Unix(1792275925, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/dienakakim/mds/lib/check"
+	"github.com/dienakakim/mds/lib/options"
+	"github.com/dienakakim/mds/lib/resolve"
+)
+
+// checkMain is the driver code for the check mode, which reports broken links in a whole tree. It exits with status
+// 1 if any link is broken, so that it can fail CI builds.
+func checkMain(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	markdownSettings := markdownFlags(fs)
+	src := fs.String("src", ".", "source directory")
+	asJSON := fs.Bool("json", false, "write the report as JSON")
+	external := fs.Bool("external", false, "list external links, without fetching them")
+
+	// Markdown is rendered as by the server, with the settings of the same configuration files and environment
+	if _, err := options.LoadKnown(fs, ".", args); err != nil {
+		log.Fatal(err)
+	}
+	markdown, err := markdownSettings()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Documents are parsed as the server would, so that wiki links and heading IDs match
+	root, err := resolve.New(*src, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	index := newIndex(*src, root)
+	if err := index.Refresh(); err != nil {
+		log.Fatal(err)
+	}
+	gm := goldmarkInitializer(markdown.Highlight["light"], false, markdown, index)
+
+	report, err := check.Check(*src, gm, *external)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *asJSON {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(report.Broken) > 0 {
+		os.Exit(1)
+	}
+}
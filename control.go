@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dienakakim/mds/lib/api"
+	"github.com/dienakakim/mds/lib/certs"
+	"github.com/dienakakim/mds/lib/options"
+	"github.com/dienakakim/mds/lib/resolve"
+	"github.com/dienakakim/mds/lib/settings"
+	. "github.com/dienakakim/mds/lib/structs"
+	"github.com/dienakakim/mds/lib/theme"
+)
+
+// control carries out the operations of the pause menu and of the API on the running server.
+type control struct {
+	store  *settings.Store
+	root   *resolve.Root
+	themes *theme.Registry
+
+	// Directory mds was started from, where the project configuration file is
+	workDir string
+
+	// Settings as last read, so that reloading only applies what changed since
+	mu     sync.Mutex
+	loaded map[string]string
+}
+
+// newControl creates the control of a server started from workDir with the current flags.
+func newControl(store *settings.Store, root *resolve.Root, themes *theme.Registry, workDir string) *control {
+	loaded := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		loaded[f.Name] = f.Value.String()
+	})
+	return &control{store: store, root: root, themes: themes, workDir: workDir, loaded: loaded}
+}
+
+// status reports the settings of config.
+func status(config Config) api.Status {
+	return api.Status{File: filepath.ToSlash(config.FileName), Dark: config.DarkMode, Theme: config.Theme}
+}
+
+// Status returns the current settings.
+func (c *control) Status() api.Status {
+	return status(c.store.Snapshot())
+}
+
+// ToggleDark switches between the dark and the light theme.
+func (c *control) ToggleDark() api.Status {
+	return status(c.store.Update(func(config *Config) {
+		config.DarkMode = !config.DarkMode
+		config.Style = c.style(config.DarkMode)
+		config.Theme = ""
+	}))
+}
+
+// style returns the stylesheet of the dark or the light theme, as loaded at startup.
+func (c *control) style(dark bool) template.CSS {
+	name := "light"
+	if dark {
+		name = "dark"
+	}
+	t, _ := c.themes.Get(name)
+	return t.Style
+}
+
+// SetFile makes the served file name the one shown at /.
+func (c *control) SetFile(name string) (api.Status, error) {
+	fileName, err := c.root.Resolve(filepath.ToSlash(name))
+	if err == nil {
+		_, err = os.Stat(fileName)
+	}
+	if err != nil {
+		return c.Status(), fmt.Errorf("cannot serve %q: %v", name, err)
+	}
+	return status(c.store.Update(func(config *Config) {
+		config.FileName = fileName
+	})), nil
+}
+
+// Reload reads the configuration files and the environment again. The default file, dark mode and theme change
+// right away, while other settings need a restart.
+func (c *control) Reload() (api.Reloaded, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	values, err := options.Reread(flag.CommandLine, c.workDir, os.Args[1:], "help", "print-config")
+	if err != nil {
+		return api.Reloaded{}, err
+	}
+	if values["file"] == "" {
+		// As at startup
+		values["file"] = "."
+	}
+	var names []string
+	for name, value := range values {
+		if value != c.loaded[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	reloaded := api.Reloaded{Applied: []string{}, Restart: []string{}}
+	for _, name := range names {
+		value := values[name]
+		switch name {
+		case "dark":
+			dark, err := strconv.ParseBool(value)
+			if err != nil {
+				return reloaded, fmt.Errorf("dark: %v", err)
+			}
+			c.store.Update(func(config *Config) {
+				config.DarkMode, config.Style = dark, c.style(dark)
+			})
+		case "theme":
+			if _, ok := c.themes.Get(value); value != "" && !ok {
+				return reloaded, fmt.Errorf("unknown theme %q", value)
+			}
+			c.store.Update(func(config *Config) {
+				config.Theme = value
+			})
+		case "file":
+			if _, err := c.SetFile(value); err != nil {
+				return reloaded, err
+			}
+		default:
+			reloaded.Restart = append(reloaded.Restart, name)
+			continue
+		}
+		reloaded.Applied = append(reloaded.Applied, name)
+	}
+	c.loaded = values
+	return reloaded, nil
+}
+
+// Files lists the files that can be served, skipping hidden ones.
+func (c *control) Files() []string {
+	files := []string{}
+	filepath.Walk(".", func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if p != "." && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			if _, err := c.root.Resolve(filepath.ToSlash(p)); err == nil {
+				files = append(files, filepath.ToSlash(p))
+			}
+		}
+		return nil
+	})
+	return files
+}
+
+// ctlMain controls a running server through its API.
+func ctlMain(args []string) {
+	fs := flag.NewFlagSet("ctl", flag.ExitOnError)
+	host := fs.String("host", "127.0.0.1", "host of the server")
+	port := fs.String("port", "8080", "port of the server")
+	socket := fs.String("socket", "", "Unix socket of the server, instead of --host and --port")
+	useTLS := fs.Bool("tls", false, "connect with HTTPS")
+	token := fs.String("token", os.Getenv("MDS_API_TOKEN"), "API token")
+	fs.Parse(args)
+
+	client := &api.Client{Token: *token, HTTP: &http.Client{}}
+	transport := &http.Transport{}
+	instance := *port
+	scheme := "http"
+	if *useTLS {
+		transport.TLSClientConfig = certs.ClientConfig()
+		scheme = "https"
+	}
+	if *socket != "" {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", *socket)
+		}
+		instance = "unix-" + filepath.Base(*socket)
+		client.URL = scheme + "://localhost"
+	} else {
+		client.URL = scheme + "://" + net.JoinHostPort(*host, *port)
+	}
+	client.HTTP.Transport = transport
+	if client.Token == "" {
+		t, err := api.LoadToken(instance)
+		if err != nil {
+			log.Fatalf("No API token given, and none left by the server: %v", err)
+		}
+		client.Token = t
+	}
+
+	var s api.Status
+	var err error
+	switch command := fs.Arg(0); {
+	case command == "" || command == "status":
+		err = client.Call(http.MethodGet, "", nil, &s)
+	case command == "dark":
+		err = client.Call(http.MethodPost, "/dark", nil, &s)
+	case command == "file" && fs.NArg() == 2:
+		err = client.Call(http.MethodPost, "/file", map[string]string{"file": fs.Arg(1)}, &s)
+	case command == "reload":
+		var reloaded api.Reloaded
+		if err := client.Call(http.MethodPost, "/reload", nil, &reloaded); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("applied: %s\nneeds restart: %s\n", strings.Join(reloaded.Applied, ", "),
+			strings.Join(reloaded.Restart, ", "))
+		return
+	case command == "files":
+		var files struct {
+			Files []string `json:"files"`
+		}
+		if err := client.Call(http.MethodGet, "/files", nil, &files); err != nil {
+			log.Fatal(err)
+		}
+		for _, f := range files.Files {
+			fmt.Println(f)
+		}
+		return
+	default:
+		usage("Unknown ctl command, expected status, dark, file NAME, reload or files")
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("file: %s\ndark: %t\n", s.File, s.Dark)
+	if s.Theme != "" {
+		fmt.Printf("theme: %s\n", s.Theme)
+	}
+}
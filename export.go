@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+
+	"github.com/dienakakim/mds/lib/export"
+	"github.com/dienakakim/mds/lib/options"
+	"github.com/dienakakim/mds/lib/resolve"
+	. "github.com/dienakakim/mds/lib/structs"
+)
+
+// exportMain is the driver code for the export mode, which renders a whole tree to static HTML files.
+func exportMain(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dark := fs.Bool("dark", true, "enable dark theme")
+	markdownSettings := markdownFlags(fs)
+	src := fs.String("src", ".", "source directory")
+	out := fs.String("out", "site", "output directory")
+	templateFile := fs.String("template", "", "page template file")
+	cssFile := fs.String("css", "", "stylesheet file")
+
+	// Markdown is rendered as by the server, with the settings of the same configuration files and environment
+	if _, err := options.LoadKnown(fs, ".", args); err != nil {
+		log.Fatal(err)
+	}
+	markdown, err := markdownSettings()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	templ, err := loadTemplate(*templateFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	css, err := loadStyle(*cssFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	style := stylesheet(*dark)
+	if css != nil {
+		style = template.CSS(css)
+	}
+
+	// Wiki links are resolved against the exported tree
+	root, err := resolve.New(*src, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	index := newIndex(*src, root)
+	if err := index.Refresh(); err != nil {
+		log.Fatal(err)
+	}
+
+	config := Config{DarkMode: *dark, Math: markdown.Math, Style: style}
+	gm := goldmarkInitializer(markdown.Highlight["light"], false, markdown, index)
+	if *dark {
+		gm = goldmarkInitializer(markdown.Highlight["dark"], true, markdown, index)
+	}
+	if err := export.Export(*src, *out, gm, templ, config); err != nil {
+		log.Fatal(err)
+	}
+	// Pages reference the favicon from the site root
+	if err := ioutil.WriteFile(filepath.Join(*out, "favicon.ico"), MustAsset("assets/favicon.ico"), 0644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Exported \"%s\" to \"%s\"", *src, *out)
+}
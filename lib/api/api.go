@@ -0,0 +1,198 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Prefix of the paths of the API
+const Prefix = "/_mds/api"
+
+// Status is the state of a running server that the API can change.
+type Status struct {
+	File  string `json:"file"`
+	Dark  bool   `json:"dark"`
+	Theme string `json:"theme,omitempty"`
+}
+
+// Reloaded lists the settings that changed when the configuration was read again: those now in effect, and those
+// that only take effect once the server is restarted.
+type Reloaded struct {
+	Applied []string `json:"applied"`
+	Restart []string `json:"restart"`
+}
+
+// Controller carries out the operations of the API.
+type Controller interface {
+	Status() Status
+	ToggleDark() Status
+	SetFile(name string) (Status, error)
+	Reload() (Reloaded, error)
+	Files() []string
+}
+
+// Handler serves the API to clients presenting token as a bearer token:
+//
+//	GET  /_mds/api         the status
+//	POST /_mds/api/dark    toggle dark mode, returning the status
+//	POST /_mds/api/file    make {"file": NAME} the default file, returning the status
+//	POST /_mds/api/reload  read the configuration files and environment again
+//	GET  /_mds/api/files   list the served files
+func Handler(token string, c Controller) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s", r.Method, r.URL.Path)
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mds"`)
+			fail(w, http.StatusUnauthorized, errors.New("missing or wrong API token"))
+			return
+		}
+		operation := strings.Trim(strings.TrimPrefix(r.URL.Path, Prefix), "/")
+		method := http.MethodPost
+		if operation == "" || operation == "files" {
+			method = http.MethodGet
+		}
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			fail(w, http.StatusMethodNotAllowed, fmt.Errorf("%s needs %s", r.URL.Path, method))
+			return
+		}
+
+		var result interface{}
+		switch operation {
+		case "":
+			result = c.Status()
+		case "dark":
+			result = c.ToggleDark()
+		case "file":
+			var request struct {
+				File string `json:"file"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				fail(w, http.StatusBadRequest, err)
+				return
+			}
+			status, err := c.SetFile(request.File)
+			if err != nil {
+				fail(w, http.StatusBadRequest, err)
+				return
+			}
+			result = status
+		case "reload":
+			reloaded, err := c.Reload()
+			if err != nil {
+				fail(w, http.StatusInternalServerError, err)
+				return
+			}
+			result = reloaded
+		case "files":
+			result = map[string][]string{"files": c.Files()}
+		default:
+			fail(w, http.StatusNotFound, fmt.Errorf("no operation %q", operation))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// fail reports err as a JSON error.
+func fail(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// Client calls the API of the server at URL, such as http://127.0.0.1:8080, through HTTP if not nil.
+type Client struct {
+	URL   string
+	Token string
+	HTTP  *http.Client
+}
+
+// Call sends in, if not nil, as the JSON body of a request for the API path, and decodes the response into out.
+func (c *Client) Call(method, path string, in, out interface{}) error {
+	var body strings.Builder
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.URL, "/")+Prefix+path, strings.NewReader(body.String()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", "application/json")
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(res.Body).Decode(&e) != nil || e.Error == "" {
+			e.Error = res.Status
+		}
+		return errors.New(e.Error)
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// NewToken returns a random token.
+func NewToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// TokenFile returns the file in which a server leaves its token for local clients. The server is known by instance:
+// the port it listens on, or the name of its Unix socket.
+func TokenFile(instance string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mds", "api-"+instance+".token"), nil
+}
+
+// SaveToken writes token to the token file of instance, readable only by the current user.
+func SaveToken(instance, token string) (string, error) {
+	fileName, err := TokenFile(instance)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), 0700); err != nil {
+		return "", err
+	}
+	return fileName, ioutil.WriteFile(fileName, []byte(token+"\n"), 0600)
+}
+
+// LoadToken reads the token of instance from its token file.
+func LoadToken(instance string) (string, error) {
+	fileName, err := TokenFile(instance)
+	if err != nil {
+		return "", err
+	}
+	token, err := ioutil.ReadFile(fileName)
+	return strings.TrimSpace(string(token)), err
+}
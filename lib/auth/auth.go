@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cookie holding the access token once a browser has presented it
+const cookieName = "mds_token"
+
+// Users are the accounts of an htpasswd file, with their bcrypt hashes.
+type Users struct {
+	hashes map[string][]byte
+	dummy  []byte
+
+	// Digests of the passwords that matched, as checking bcrypt hashes takes a while and a page makes many requests
+	mu       sync.Mutex
+	verified map[string][sha256.Size]byte
+}
+
+// LoadUsers reads an htpasswd file, as written by htpasswd -B. Only bcrypt hashes are accepted.
+func LoadUsers(fileName string) (*Users, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	users := &Users{hashes: make(map[string][]byte), verified: make(map[string][sha256.Size]byte)}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		name, hash := entry, ""
+		if i := strings.Index(entry, ":"); i >= 0 {
+			name, hash = entry[:i], entry[i+1:]
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); name == "" || err != nil {
+			return nil, fmt.Errorf("%s:%d: expected user:bcrypt-hash, as from htpasswd -B", fileName, line)
+		}
+		users.hashes[name] = []byte(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(users.hashes) == 0 {
+		return nil, fmt.Errorf("%s: no users", fileName)
+	}
+	users.dummy, err = bcrypt.GenerateFromPassword(nil, bcrypt.DefaultCost)
+	return users, err
+}
+
+// Check reports whether password is that of user.
+func (u *Users) Check(user, password string) bool {
+	digest := sha256.Sum256([]byte(password))
+	u.mu.Lock()
+	known, ok := u.verified[user]
+	u.mu.Unlock()
+	if ok && subtle.ConstantTimeCompare(known[:], digest[:]) == 1 {
+		return true
+	}
+
+	hash, ok := u.hashes[user]
+	if !ok {
+		// Take as long as for an existing user
+		bcrypt.CompareHashAndPassword(u.dummy, []byte(password))
+		return false
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+		return false
+	}
+	u.mu.Lock()
+	u.verified[user] = digest
+	u.mu.Unlock()
+	return true
+}
+
+// Basic only lets requests through to next with the user name and password of one of users, using HTTP Basic
+// authentication.
+func Basic(users *Users, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); ok && users.Check(user, password) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="mds", charset="UTF-8"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// Token only lets requests through to next with token, given once as the token query parameter, such as in a link
+// shared with others, and from then on by a cookie. The parameter is removed from the URL by a redirect, so that it
+// does not stay in the history or get sent to other sites.
+func Token(token string, next http.Handler) http.Handler {
+	valid := func(given string) bool {
+		return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if given, ok := query["token"]; ok && valid(given[0]) {
+			http.SetCookie(w, &http.Cookie{Name: cookieName, Value: token, Path: "/", HttpOnly: true, Secure: r.TLS != nil,
+				SameSite: http.SameSiteLaxMode})
+			query.Del("token")
+			u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+			http.Redirect(w, r, u.String(), http.StatusSeeOther)
+			return
+		}
+		if c, err := r.Cookie(cookieName); err == nil && valid(c.Value) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, "Unauthorized: open the link with the access token printed by mds", http.StatusUnauthorized)
+	})
+}
+
+// Except passes requests for prefix and the paths below it straight to next, and all others to protected, for parts
+// of the server, such as the API, that check credentials of their own.
+func Except(prefix string, next, protected http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(prefix, next)
+	mux.Handle(prefix+"/", next)
+	mux.Handle("/", protected)
+	return mux
+}
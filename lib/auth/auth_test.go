@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dienakakim/mds/lib/api"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ok stands for the pages behind the sign-in.
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("page"))
+})
+
+// control answers the API with a fixed status.
+type control struct{}
+
+func (control) Status() api.Status                      { return api.Status{File: "README.md"} }
+func (control) ToggleDark() api.Status                  { return api.Status{Dark: true} }
+func (control) SetFile(name string) (api.Status, error) { return api.Status{File: name}, nil }
+func (control) Reload() (api.Reloaded, error)           { return api.Reloaded{}, nil }
+func (control) Files() []string                         { return nil }
+
+// serve sends a request for target through h, with the given cookie and header if they are not empty.
+func serve(h http.Handler, target string, cookie *http.Cookie, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestBasic(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileName := filepath.Join(t.TempDir(), "users.htpasswd")
+	if err := ioutil.WriteFile(fileName, []byte("# users\nalice:"+string(hash)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	users, err := LoadUsers(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := Basic(users, ok)
+
+	tests := []struct {
+		name           string
+		user, password string
+		code           int
+	}{
+		{"right user and password", "alice", "secret", http.StatusOK},
+		{"wrong user", "bob", "secret", http.StatusUnauthorized},
+		{"wrong password", "alice", "guess", http.StatusUnauthorized},
+		// A second time, when the password that matched is remembered
+		{"right password again", "alice", "secret", http.StatusOK},
+		{"wrong password again", "alice", "Secret", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.SetBasicAuth(tt.user, tt.password)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.code)
+		}
+		if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: no WWW-Authenticate header", tt.name)
+		}
+	}
+	if w := serve(h, "/", nil, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("no credentials: got %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestLoadUsersRejectsOtherHashes(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "users.htpasswd")
+	if err := ioutil.WriteFile(fileName, []byte("alice:$apr1$salt$hash\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadUsers(fileName); err == nil {
+		t.Error("MD5 hash accepted")
+	}
+}
+
+func TestToken(t *testing.T) {
+	h := Token("abc", ok)
+
+	// The token in the query sets the cookie and is removed from the URL
+	w := serve(h, "/docs/a.md?token=abc&x=1", nil, nil)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("token in query: got %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if location := w.Header().Get("Location"); location != "/docs/a.md?x=1" {
+		t.Errorf("redirected to %q, want /docs/a.md?x=1", location)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != cookieName || cookies[0].Value != "abc" || !cookies[0].HttpOnly {
+		t.Fatalf("got cookies %v", cookies)
+	}
+
+	// The cookie alone lets later requests through
+	if w := serve(h, "/docs/a.md?x=1", cookies[0], nil); w.Code != http.StatusOK || w.Body.String() != "page" {
+		t.Errorf("cookie: got %d %q", w.Code, w.Body.String())
+	}
+
+	for _, tt := range []struct {
+		name   string
+		target string
+		cookie *http.Cookie
+	}{
+		{"no token", "/", nil},
+		{"wrong token in query", "/?token=abd", nil},
+		{"empty token in query", "/?token=", nil},
+		{"wrong cookie", "/", &http.Cookie{Name: cookieName, Value: "abd"}},
+	} {
+		if w := serve(h, tt.target, tt.cookie, nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestExcept(t *testing.T) {
+	sm := http.NewServeMux()
+	sm.Handle("/", ok)
+	sm.Handle(api.Prefix, api.Handler("api-token", control{}))
+	sm.Handle(api.Prefix+"/", api.Handler("api-token", control{}))
+	h := Except(api.Prefix, sm, Token("abc", sm))
+	bearer := map[string]string{"Authorization": "Bearer api-token"}
+
+	tests := []struct {
+		name   string
+		target string
+		header map[string]string
+		code   int
+	}{
+		// The API checks its bearer token instead of the access token
+		{"API with bearer token", api.Prefix, bearer, http.StatusOK},
+		{"API below the prefix", api.Prefix + "/files", bearer, http.StatusOK},
+		{"API without bearer token", api.Prefix, nil, http.StatusUnauthorized},
+		{"API with access token", api.Prefix + "?token=abc", nil, http.StatusUnauthorized},
+		// Pages still need the access token, even with the API's
+		{"page with bearer token", "/", bearer, http.StatusUnauthorized},
+		{"page with access token", "/?token=abc", nil, http.StatusSeeOther},
+		{"page next to the API", "/_mds/apix", bearer, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		w := serve(h, tt.target, nil, tt.header)
+		if w.Code != tt.code {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.code)
+		}
+		if challenge := w.Header().Get("WWW-Authenticate"); tt.header == nil && tt.code == http.StatusUnauthorized &&
+			!strings.HasPrefix(challenge, "Bearer ") {
+			t.Errorf("%s: asked for %q instead of the API token", tt.name, challenge)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	. "github.com/dienakakim/mds/lib/structs"
+)
+
+// Estimated bookkeeping cost of an entry, on top of its rendered text, and of each link it holds
+const (
+	entryOverhead = 256
+	linkOverhead  = 64
+)
+
+// Key identifies one rendering of a file. A file that is modified gets a new modification time or size, and so a new
+// key; Options holds whatever else changes the output, such as the theme and the enabled extensions.
+type Key struct {
+	Path    string
+	ModTime time.Time
+	Size    int64
+	Options string
+}
+
+// Stats are the counters of a Cache.
+type Stats struct {
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"`
+	MaxBytes  int64  `json:"maxBytes"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// entry is a cached page, stored in the recency list.
+type entry struct {
+	key   Key
+	value RenderedHTML
+	size  int64
+}
+
+// Cache is a least-recently-used cache of rendered pages, bounded by the estimated size of its entries. It is safe for
+// concurrent use. A Cache with no room at all never stores anything.
+type Cache struct {
+	mu     sync.Mutex
+	max    int64
+	size   int64
+	recent *list.List
+	items  map[Key]*list.Element
+	stats  Stats
+}
+
+// New creates a Cache holding up to maxBytes of rendered pages.
+func New(maxBytes int64) *Cache {
+	return &Cache{max: maxBytes, recent: list.New(), items: make(map[Key]*list.Element)}
+}
+
+// Get returns the page cached under key, marking it as recently used.
+func (c *Cache) Get(key Key) (RenderedHTML, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.stats.Hits++
+		c.recent.MoveToFront(e)
+		return e.Value.(*entry).value, true
+	}
+	c.stats.Misses++
+	return RenderedHTML{}, false
+}
+
+// Add caches value under key, without its stylesheet, which is shared by every page of a theme and attached again
+// when the page is served. Renderings of older versions of the same file are dropped, and then the least recently used
+// pages until the cache fits in its size.
+func (c *Cache) Add(key Key, value RenderedHTML) {
+	value.Style = ""
+	size := int64(entryOverhead+len(value.Body)+len(value.TOC)+len(value.Title)+len(value.Path)) + navSize(value.Nav)
+	for _, link := range value.Backlinks {
+		size += int64(linkOverhead + len(link.Path) + len(link.Title))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if size > c.max {
+		return
+	}
+	for e := c.recent.Front(); e != nil; {
+		next := e.Next()
+		if old := e.Value.(*entry).key; old.Path == key.Path && (old.ModTime != key.ModTime || old.Size != key.Size) {
+			c.remove(e)
+		}
+		e = next
+	}
+	if e, ok := c.items[key]; ok {
+		c.remove(e)
+	}
+	c.items[key] = c.recent.PushFront(&entry{key: key, value: value, size: size})
+	c.size += size
+	for c.size > c.max {
+		c.remove(c.recent.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Stats returns a snapshot of the counters.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.items)
+	stats.Bytes = c.size
+	stats.MaxBytes = c.max
+	return stats
+}
+
+// navSize estimates the memory taken by the entries of a navigation tree, which every page holds a copy of.
+func navSize(items []NavItem) int64 {
+	var size int64
+	for _, item := range items {
+		size += int64(linkOverhead+len(item.Path)+len(item.Title)) + navSize(item.Children)
+	}
+	return size
+}
+
+// remove drops an element from the cache.
+func (c *Cache) remove(e *list.Element) {
+	ent := c.recent.Remove(e).(*entry)
+	delete(c.items, ent.key)
+	c.size -= ent.size
+}
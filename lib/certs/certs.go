@@ -0,0 +1,129 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Lifetime of self-signed certificates, and how long before expiry they are replaced
+const (
+	lifetime = 365 * 24 * time.Hour
+	renewal  = 30 * 24 * time.Hour
+)
+
+// Files returns where the self-signed certificate and its key are kept: in the mds folder of the user config dir.
+func Files() (certFile, keyFile string, err error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", "", err
+	}
+	dir = filepath.Join(dir, "mds", "tls")
+	return filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), nil
+}
+
+// SelfSigned returns the files of a self-signed certificate valid for hosts, which are names or IP addresses. The
+// certificate is generated once and reused until it is about to expire or does not cover every host.
+func SelfSigned(hosts []string) (certFile, keyFile string, err error) {
+	certFile, keyFile, err = Files()
+	if err != nil {
+		return "", "", err
+	}
+	if cert, err := load(certFile); err == nil && time.Until(cert.NotAfter) > renewal && covers(cert, hosts) {
+		return certFile, keyFile, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"mds"}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(lifetime),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return "", "", err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certFile), 0700); err != nil {
+		return "", "", err
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		0600); err != nil {
+		return "", "", err
+	}
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		0644); err != nil {
+		return "", "", err
+	}
+	return certFile, keyFile, nil
+}
+
+// load reads the first certificate of a PEM file.
+func load(certFile string) (*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, os.ErrInvalid
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// covers reports whether cert is valid for every host.
+func covers(cert *x509.Certificate, hosts []string) bool {
+	for _, h := range hosts {
+		if cert.VerifyHostname(h) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// ClientConfig returns a TLS configuration trusting the system roots and the self-signed certificate, if any.
+func ClientConfig() *tls.Config {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if certFile, _, err := Files(); err == nil {
+		if cert, err := load(certFile); err == nil {
+			pool.AddCert(cert)
+		}
+	}
+	return &tls.Config{RootCAs: pool}
+}
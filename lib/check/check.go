@@ -0,0 +1,158 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	. "github.com/dienakakim/mds/lib/render"
+	"github.com/yuin/goldmark"
+)
+
+// Problem is a link that could not be followed, or an external link, which is never fetched.
+type Problem struct {
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	Destination string `json:"destination"`
+	Reason      string `json:"reason"`
+}
+
+// Report is the outcome of checking a tree. Paths are slash-separated and relative to the checked directory.
+type Report struct {
+	Files    int       `json:"files"`
+	Links    int       `json:"links"`
+	Broken   []Problem `json:"broken"`
+	External []Problem `json:"external,omitempty"`
+}
+
+// document is a scanned Markdown file.
+type document struct {
+	refs    []Reference
+	anchors map[string]bool
+}
+
+// Check parses every Markdown file below dir with gm and verifies that the local files they link to or embed exist,
+// and that fragments pointing into Markdown files name one of their anchors. External links are listed in the report
+// only if external is set. Hidden files and folders are skipped, as they are by the server.
+func Check(dir string, gm goldmark.Markdown, external bool) (*Report, error) {
+	docs := make(map[string]*document)
+	err := filepath.Walk(dir, func(fileName string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && fileName != dir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, fileName)
+		if err != nil {
+			return err
+		}
+		source, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return err
+		}
+		refs, anchors := ScanLinks(gm, source)
+		docs[filepath.ToSlash(rel)] = &document{refs: refs, anchors: anchors}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(docs))
+	for name := range docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	report := &Report{Files: len(docs), Broken: []Problem{}}
+	for _, name := range names {
+		for _, ref := range docs[name].refs {
+			report.Links++
+			problem := Problem{File: name, Line: ref.Line, Destination: ref.Destination}
+			reason, isExternal := verify(dir, name, ref.Destination, docs)
+			switch {
+			case isExternal && external:
+				problem.Reason = "external, not checked"
+				report.External = append(report.External, problem)
+			case reason != "":
+				problem.Reason = reason
+				report.Broken = append(report.Broken, problem)
+			}
+		}
+	}
+	return report, nil
+}
+
+// verify checks a destination found in the document name, returning why it is broken, or an empty string. It reports
+// true instead if the destination is external.
+func verify(dir, name, dest string, docs map[string]*document) (string, bool) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return "invalid URL", false
+	}
+	if u.Scheme != "" || u.Host != "" || strings.HasPrefix(dest, "//") {
+		return "", true
+	}
+
+	// Absolute paths are served from the checked directory
+	target := name
+	if u.Path != "" {
+		if strings.HasPrefix(u.Path, "/") {
+			target = path.Clean(u.Path[1:])
+		} else {
+			target = path.Join(path.Dir(name), u.Path)
+		}
+		if target == ".." || strings.HasPrefix(target, "../") {
+			return "outside the checked directory", false
+		}
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(target))); err != nil {
+			return "no such file", false
+		}
+	}
+
+	if u.Fragment == "" {
+		return "", false
+	}
+	doc, ok := docs[target]
+	if !ok {
+		// Anchors are only known in Markdown files
+		return "", false
+	}
+	if !doc.anchors[u.Fragment] {
+		return fmt.Sprintf("no heading with ID %q", u.Fragment), false
+	}
+	return "", false
+}
+
+// WriteText writes the report for humans, one problem per line followed by a summary.
+func (r *Report) WriteText(w io.Writer) error {
+	for _, problems := range [][]Problem{r.Broken, r.External} {
+		for _, p := range problems {
+			if _, err := fmt.Fprintf(w, "%s:%d: %s: %s\n", p.File, p.Line, p.Destination, p.Reason); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d broken of %d links in %d files\n", len(r.Broken), r.Links, r.Files)
+	return err
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
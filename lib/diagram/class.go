@@ -0,0 +1,185 @@
+package diagram
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Class diagram syntax
+var (
+	classID       = `[\p{L}\p{N}_]+(?:~[^~]*~)?`
+	classRelation = regexp.MustCompile(`^(` + classID + `)\s*(?:"([^"]*)"\s*)?(<\||\*|o|<)?(--|\.\.)(\|>|\*|o|>)?\s*` +
+		`(?:"([^"]*)"\s*)?(` + classID + `)\s*(?::\s*(.*))?$`)
+	classDeclaration = regexp.MustCompile(`^class\s+(` + classID + `)\s*(?:\["([^"]*)"\])?\s*(\{)?\s*(\})?$`)
+	classMember      = regexp.MustCompile(`^(` + classID + `)\s*:\s*(.*)$`)
+	classAnnotation  = regexp.MustCompile(`^<<([^>]*)>>\s*(` + classID + `)?$`)
+	classNote        = regexp.MustCompile(`^note\s+(?:for\s+(` + classID + `)\s+)?"([^"]*)"$`)
+	classGeneric     = regexp.MustCompile(`~([^~]*)~`)
+	classStyle       = regexp.MustCompile(`:::[\p{L}\p{N}_-]+`)
+)
+
+// Statements that only affect styling, interaction or accessibility, which are skipped
+var classIgnored = []string{"classDef ", "cssClass ", "style ", "click ", "link ", "callback ", "accTitle", "accDescr"}
+
+// Ends of class relationships, as the markers drawn for them
+var classMarkers = map[string]string{
+	"<|": markerTriangle, "|>": markerTriangle, "*": markerDiamond, "o": markerODiamond, "<": markerArrow,
+	">": markerArrow,
+}
+
+// classBox is a class with its members, drawn once the whole diagram is read.
+type classBox struct {
+	node       *Node
+	annotation string
+	attributes []string
+	methods    []string
+}
+
+// classParser builds a class diagram.
+type classParser struct {
+	g       *Graph
+	classes map[*Node]*classBox
+
+	// Class whose body is being read, and the namespaces around the current statement
+	open       *classBox
+	namespaces int
+	notes      int
+}
+
+// parseClasses parses a Mermaid class diagram: classes with their annotations, attributes and methods, relationships
+// with their labels and cardinalities, and notes. Namespaces are flattened into the graph.
+func parseClasses(header string, body []mermaidLine) (*Graph, error) {
+	p := &classParser{g: newGraph(), classes: make(map[*Node]*classBox)}
+	for _, line := range body {
+		stmt := strings.TrimSpace(classStyle.ReplaceAllString(line.text, ""))
+		if err := p.statement(line, stmt); err != nil {
+			return nil, err
+		}
+	}
+	if p.open != nil {
+		return nil, fmt.Errorf("mermaid: class %s not closed", p.open.node.ID)
+	}
+	for _, n := range p.g.Nodes {
+		if c, ok := p.classes[n]; ok {
+			c.layOut()
+		}
+	}
+	return p.g, nil
+}
+
+// statement parses a single statement, or a member inside the body of a class.
+func (p *classParser) statement(line mermaidLine, stmt string) error {
+	if p.open != nil {
+		if stmt == "}" {
+			p.open = nil
+		} else if m := classAnnotation.FindStringSubmatch(stmt); m != nil && m[2] == "" {
+			p.open.annotation = m[1]
+		} else {
+			p.open.add(stmt)
+		}
+		return nil
+	}
+	if strings.HasPrefix(stmt, "direction ") {
+		direction := strings.TrimSpace(strings.TrimPrefix(stmt, "direction "))
+		p.g.LeftRight = direction == "LR" || direction == "RL"
+		return nil
+	}
+	for _, prefix := range classIgnored {
+		if strings.HasPrefix(stmt, prefix) {
+			return nil
+		}
+	}
+	switch {
+	case strings.HasPrefix(stmt, "namespace ") && strings.HasSuffix(stmt, "{"):
+		p.namespaces++
+		return nil
+	case stmt == "}":
+		if p.namespaces == 0 {
+			return line.errorf("} without a class or namespace")
+		}
+		p.namespaces--
+		return nil
+	}
+	if m := classRelation.FindStringSubmatch(stmt); m != nil {
+		e := p.g.edge(p.class(m[1]).node, p.class(m[7]).node)
+		e.Dashed = m[4] == ".."
+		e.Tail, e.TailArrow = classMarkers[m[3]], m[3] != ""
+		e.Head, e.HeadArrow = classMarkers[m[5]], m[5] != ""
+		label := mermaidLabel(m[8])
+		if m[2] != "" || m[6] != "" {
+			label = strings.TrimSpace(label + "\n" + strings.TrimSpace(m[2]+" : "+m[6]))
+		}
+		e.Label = label
+		return nil
+	}
+	if m := classDeclaration.FindStringSubmatch(stmt); m != nil {
+		c := p.class(m[1])
+		if m[2] != "" {
+			c.node.Label = m[2]
+		}
+		if m[3] != "" && m[4] == "" {
+			p.open = c
+		}
+		return nil
+	}
+	if m := classAnnotation.FindStringSubmatch(stmt); m != nil && m[2] != "" {
+		p.class(m[2]).annotation = m[1]
+		return nil
+	}
+	if m := classNote.FindStringSubmatch(stmt); m != nil {
+		p.notes++
+		note := p.g.node(fmt.Sprintf("\x00note%d", p.notes), shapeBox)
+		note.Label = mermaidLabel(strings.ReplaceAll(m[2], `\n`, "\n"))
+		note.Dashed = true
+		if m[1] != "" {
+			link := p.g.edge(note, p.class(m[1]).node)
+			link.Dashed = true
+		}
+		return nil
+	}
+	if m := classMember.FindStringSubmatch(stmt); m != nil {
+		p.class(m[1]).add(m[2])
+		return nil
+	}
+	return line.errorf("expected a class or a relationship at %q", stmt)
+}
+
+// class returns the class with the given name, which may have type parameters between tildes.
+func (p *classParser) class(name string) *classBox {
+	id := classGeneric.ReplaceAllString(name, "")
+	n := p.g.node(id, shapeClass)
+	c, ok := p.classes[n]
+	if !ok {
+		c = &classBox{node: n}
+		n.Label = classGeneric.ReplaceAllString(name, "<$1>")
+		p.classes[n] = c
+	}
+	return c
+}
+
+// add adds a member to a class: a method if it has parentheses, and an attribute otherwise.
+func (c *classBox) add(member string) {
+	member = strings.TrimSpace(classGeneric.ReplaceAllString(member, "<$1>"))
+	switch {
+	case member == "":
+	case strings.Contains(member, "("):
+		c.methods = append(c.methods, member)
+	default:
+		c.attributes = append(c.attributes, member)
+	}
+}
+
+// layOut sets the label of the node of the class to its annotation and name, attributes and methods.
+func (c *classBox) layOut() {
+	name := []string{c.node.Label}
+	if c.annotation != "" {
+		name = append([]string{"«" + c.annotation + "»"}, name...)
+	}
+	var labelLines []string
+	for _, section := range [][]string{name, c.attributes, c.methods} {
+		labelLines = append(labelLines, section...)
+		c.node.Sections = append(c.node.Sections, len(section))
+	}
+	c.node.Label = strings.Join(labelLines, "\n")
+}
@@ -0,0 +1,465 @@
+package diagram
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Kinds of DOT tokens
+const (
+	dotEOF = iota
+	dotID
+	dotPunct
+	dotEdgeOp
+)
+
+// dotToken is an identifier, punctuation mark or edge operator of DOT input.
+type dotToken struct {
+	kind int
+	text string
+}
+
+// dotAttr is a single name=value attribute.
+type dotAttr struct {
+	name, value string
+}
+
+// dotScope holds the node and edge attributes set by node [...] and edge [...] statements, which apply to the nodes
+// and edges created afterwards in the same graph or subgraph.
+type dotScope struct {
+	node, edge []dotAttr
+}
+
+// dotError aborts parsing.
+type dotError struct {
+	err error
+}
+
+// dotParser is a recursive descent parser for DOT, the language of Graphviz.
+type dotParser struct {
+	src      []rune
+	pos      int
+	tok      dotToken
+	g        *Graph
+	directed bool
+}
+
+// Line breaks and other tags inside HTML-like labels
+var (
+	htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlTag   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ParseDOT parses a graph written in DOT. Node, edge and graph attributes that affect the drawing are understood:
+// label, shape, style, color, fillcolor, dir, arrowhead and rankdir. Subgraphs are flattened into the graph, and ports
+// are ignored.
+func ParseDOT(src string) (g *Graph, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(dotError)
+			if !ok {
+				panic(r)
+			}
+			g, err = nil, e.err
+		}
+	}()
+	p := &dotParser{src: []rune(src), g: newGraph()}
+	p.next()
+	p.parseGraph()
+	return p.g, nil
+}
+
+// fail aborts parsing with an error.
+func (p *dotParser) fail(format string, args ...interface{}) {
+	panic(dotError{fmt.Errorf("dot: "+format, args...)})
+}
+
+// next reads the next token.
+func (p *dotParser) next() {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		p.tok = dotToken{dotEOF, ""}
+		return
+	}
+	c := p.src[p.pos]
+	switch {
+	case c == '"':
+		p.tok = dotToken{dotID, p.quoted()}
+	case c == '<':
+		p.tok = dotToken{dotID, p.html()}
+	case c == '-' && p.pos+1 < len(p.src) && (p.src[p.pos+1] == '>' || p.src[p.pos+1] == '-'):
+		p.tok = dotToken{dotEdgeOp, string(p.src[p.pos : p.pos+2])}
+		p.pos += 2
+	case strings.ContainsRune("{}[];,=:", c):
+		p.tok = dotToken{dotPunct, string(c)}
+		p.pos++
+	case isIDRune(c) || c == '-' || c == '.':
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.src) && (isIDRune(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		p.tok = dotToken{dotID, string(p.src[start:p.pos])}
+	default:
+		p.fail("unexpected character %q", c)
+	}
+}
+
+// skipSpace skips white space and comments.
+func (p *dotParser) skipSpace() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case unicode.IsSpace(c):
+			p.pos++
+		case c == '#' || c == '/' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '/':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == '/' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '*':
+			for p.pos += 2; p.pos+1 < len(p.src) && !(p.src[p.pos] == '*' && p.src[p.pos+1] == '/'); p.pos++ {
+			}
+			if p.pos+1 >= len(p.src) {
+				p.fail("unterminated comment")
+			}
+			p.pos += 2
+		default:
+			return
+		}
+	}
+}
+
+// quoted reads a double-quoted string. Escaped quotes are unescaped; other escapes are kept for label processing.
+func (p *dotParser) quoted() string {
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		c := p.src[p.pos]
+		if c == '"' {
+			p.pos++
+			return b.String()
+		}
+		if c == '\\' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '"' {
+			p.pos++
+			c = '"'
+		}
+		b.WriteRune(c)
+	}
+	p.fail("unterminated string")
+	return ""
+}
+
+// html reads an HTML-like label between angle brackets, keeping only its text.
+func (p *dotParser) html() string {
+	depth := 0
+	start := p.pos
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth == 0 {
+				p.pos++
+				inner := string(p.src[start+1 : p.pos-1])
+				inner = htmlBreak.ReplaceAllString(inner, `\n`)
+				return strings.TrimSpace(htmlTag.ReplaceAllString(inner, ""))
+			}
+		}
+	}
+	p.fail("unterminated HTML label")
+	return ""
+}
+
+// isIDRune reports whether c can appear in an unquoted identifier.
+func isIDRune(c rune) bool {
+	return c == '_' || c >= 0x80 || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// is reports whether the current token is the punctuation mark s.
+func (p *dotParser) is(s string) bool {
+	return p.tok.kind == dotPunct && p.tok.text == s
+}
+
+// isKeyword reports whether the current token is the given keyword, which DOT matches case-insensitively.
+func (p *dotParser) isKeyword(keyword string) bool {
+	return p.tok.kind == dotID && strings.EqualFold(p.tok.text, keyword)
+}
+
+// expect consumes the punctuation mark s.
+func (p *dotParser) expect(s string) {
+	if !p.is(s) {
+		p.fail("expected %q, found %q", s, p.tok.text)
+	}
+	p.next()
+}
+
+// parseGraph parses a whole graph.
+func (p *dotParser) parseGraph() {
+	if p.isKeyword("strict") {
+		p.next()
+	}
+	switch {
+	case p.isKeyword("digraph"):
+		p.directed = true
+	case p.isKeyword("graph"):
+	default:
+		p.fail("expected graph or digraph, found %q", p.tok.text)
+	}
+	p.next()
+	if p.tok.kind == dotID {
+		p.next()
+	}
+	p.expect("{")
+	p.parseStatements(&dotScope{})
+	p.expect("}")
+	if p.tok.kind != dotEOF {
+		p.fail("unexpected %q after the graph", p.tok.text)
+	}
+}
+
+// parseStatements parses statements up to a closing brace, returning the nodes they mention.
+func (p *dotParser) parseStatements(scope *dotScope) []*Node {
+	var mentioned []*Node
+	for !p.is("}") && p.tok.kind != dotEOF {
+		mentioned = append(mentioned, p.parseStatement(scope)...)
+		if p.is(";") {
+			p.next()
+		}
+	}
+	return mentioned
+}
+
+// parseStatement parses a single statement, returning the nodes it mentions.
+func (p *dotParser) parseStatement(scope *dotScope) []*Node {
+	switch {
+	case p.isKeyword("graph") || p.isKeyword("node") || p.isKeyword("edge"):
+		kind := strings.ToLower(p.tok.text)
+		p.next()
+		attrs := p.parseAttributes()
+		switch kind {
+		case "graph":
+			p.applyGraph(attrs)
+		case "node":
+			scope.node = append(scope.node, attrs...)
+		case "edge":
+			scope.edge = append(scope.edge, attrs...)
+		}
+		return nil
+	case p.isKeyword("subgraph") || p.is("{"):
+		return p.parseEdges(p.parseSubgraph(scope), scope)
+	case p.tok.kind == dotID:
+		id := p.tok.text
+		p.next()
+		if p.is("=") {
+			p.next()
+			p.applyGraph([]dotAttr{{id, p.id()}})
+			return nil
+		}
+		p.skipPort()
+		if p.tok.kind == dotEdgeOp {
+			return p.parseEdges([]*Node{p.newNode(id, scope)}, scope)
+		}
+		n := p.newNode(id, scope)
+		p.applyNode(n, p.parseAttributes())
+		return []*Node{n}
+	}
+	p.fail("unexpected %q", p.tok.text)
+	return nil
+}
+
+// parseSubgraph parses a subgraph, whose node and edge attributes only apply inside it.
+func (p *dotParser) parseSubgraph(scope *dotScope) []*Node {
+	if p.isKeyword("subgraph") {
+		p.next()
+		if p.tok.kind == dotID {
+			p.next()
+		}
+	}
+	p.expect("{")
+	inner := &dotScope{node: append([]dotAttr(nil), scope.node...), edge: append([]dotAttr(nil), scope.edge...)}
+	nodes := p.parseStatements(inner)
+	p.expect("}")
+	return nodes
+}
+
+// parseEdges parses the rest of an edge statement starting with the given nodes. Without an edge operator, it just
+// returns them.
+func (p *dotParser) parseEdges(left []*Node, scope *dotScope) []*Node {
+	mentioned := left
+	type pair struct{ from, to []*Node }
+	var pairs []pair
+	for p.tok.kind == dotEdgeOp {
+		if (p.tok.text == "->") != p.directed {
+			p.fail("%s in a %s", p.tok.text, map[bool]string{true: "digraph", false: "graph"}[p.directed])
+		}
+		p.next()
+		var right []*Node
+		switch {
+		case p.isKeyword("subgraph") || p.is("{"):
+			right = p.parseSubgraph(scope)
+		case p.tok.kind == dotID:
+			right = []*Node{p.newNode(p.tok.text, scope)}
+			p.next()
+			p.skipPort()
+		default:
+			p.fail("expected a node after the edge operator, found %q", p.tok.text)
+		}
+		pairs = append(pairs, pair{left, right})
+		mentioned = append(mentioned, right...)
+		left = right
+	}
+
+	attrs := append(append([]dotAttr(nil), scope.edge...), p.parseAttributes()...)
+	for _, pr := range pairs {
+		for _, from := range pr.from {
+			for _, to := range pr.to {
+				e := p.g.edge(from, to)
+				e.HeadArrow = p.directed
+				p.applyEdge(e, attrs)
+			}
+		}
+	}
+	return mentioned
+}
+
+// parseAttributes parses any number of [name=value, ...] lists.
+func (p *dotParser) parseAttributes() []dotAttr {
+	var attrs []dotAttr
+	for p.is("[") {
+		p.next()
+		for !p.is("]") {
+			name := p.id()
+			value := "true"
+			if p.is("=") {
+				p.next()
+				value = p.id()
+			}
+			attrs = append(attrs, dotAttr{strings.ToLower(name), value})
+			if p.is(",") || p.is(";") {
+				p.next()
+			}
+		}
+		p.next()
+	}
+	return attrs
+}
+
+// id consumes an identifier.
+func (p *dotParser) id() string {
+	if p.tok.kind != dotID {
+		p.fail("expected an identifier, found %q", p.tok.text)
+	}
+	id := p.tok.text
+	p.next()
+	return id
+}
+
+// skipPort skips the :port and :compass_point suffixes of a node ID.
+func (p *dotParser) skipPort() {
+	for p.is(":") {
+		p.next()
+		p.id()
+	}
+}
+
+// newNode returns the node with the given ID, applying the node attributes in scope if it is created.
+func (p *dotParser) newNode(id string, scope *dotScope) *Node {
+	if n, ok := p.g.index[id]; ok {
+		return n
+	}
+	n := p.g.node(id, shapeEllipse)
+	p.applyNode(n, scope.node)
+	return n
+}
+
+// applyGraph applies graph attributes.
+func (p *dotParser) applyGraph(attrs []dotAttr) {
+	for _, a := range attrs {
+		if strings.ToLower(a.name) == "rankdir" {
+			dir := strings.ToUpper(a.value)
+			p.g.LeftRight = dir == "LR" || dir == "RL"
+		}
+	}
+}
+
+// Graphviz shapes and the shapes they are drawn as
+var dotShapes = map[string]string{
+	"box": shapeBox, "rect": shapeBox, "rectangle": shapeBox, "square": shapeBox, "record": shapeBox,
+	"mrecord": shapeRounded, "note": shapeBox, "tab": shapeBox, "folder": shapeBox, "box3d": shapeBox,
+	"component": shapeBox, "cylinder": shapeBox, "ellipse": shapeEllipse, "oval": shapeEllipse,
+	"circle": shapeCircle, "doublecircle": shapeCircle, "point": shapeCircle, "diamond": shapeDiamond,
+	"hexagon": shapeHexagon, "plaintext": shapePlaintext, "plain": shapePlaintext, "none": shapePlaintext,
+}
+
+// applyNode applies node attributes.
+func (p *dotParser) applyNode(n *Node, attrs []dotAttr) {
+	filled := false
+	color := ""
+	for _, a := range attrs {
+		switch a.name {
+		case "label":
+			n.Label = dotLabel(a.value, n.ID)
+		case "shape":
+			if shape, ok := dotShapes[strings.ToLower(a.value)]; ok {
+				n.Shape = shape
+			} else {
+				n.Shape = shapeEllipse
+			}
+		case "style":
+			style := strings.ToLower(a.value)
+			n.Dashed = strings.Contains(style, "dashed") || strings.Contains(style, "dotted")
+			filled = strings.Contains(style, "filled")
+			if strings.Contains(style, "rounded") && n.Shape == shapeBox {
+				n.Shape = shapeRounded
+			}
+		case "color":
+			color = a.value
+			n.Stroke = a.value
+		case "fillcolor":
+			n.Fill = a.value
+		}
+	}
+	if filled && n.Fill == "" {
+		n.Fill = color
+		if n.Fill == "" {
+			n.Fill = "lightgrey"
+		}
+	}
+}
+
+// applyEdge applies edge attributes.
+func (p *dotParser) applyEdge(e *Edge, attrs []dotAttr) {
+	for _, a := range attrs {
+		switch a.name {
+		case "label":
+			e.Label = dotLabel(a.value, "")
+		case "color":
+			e.Color = a.value
+		case "style":
+			style := strings.ToLower(a.value)
+			e.Dashed = strings.Contains(style, "dashed") || strings.Contains(style, "dotted")
+			e.Bold = strings.Contains(style, "bold")
+		case "dir":
+			switch strings.ToLower(a.value) {
+			case "forward":
+				e.HeadArrow, e.TailArrow = true, false
+			case "back":
+				e.HeadArrow, e.TailArrow = false, true
+			case "both":
+				e.HeadArrow, e.TailArrow = true, true
+			case "none":
+				e.HeadArrow, e.TailArrow = false, false
+			}
+		case "arrowhead":
+			e.HeadArrow = e.HeadArrow && strings.ToLower(a.value) != "none"
+		}
+	}
+}
+
+// dotLabel expands the escapes of a DOT label: \n, \l and \r end lines, and \N is the node ID.
+func dotLabel(label, id string) string {
+	r := strings.NewReplacer(`\n`, "\n", `\l`, "\n", `\r`, "\n", `\N`, id, `\\`, `\`)
+	return strings.TrimRight(r.Replace(label), "\n")
+}
@@ -0,0 +1,91 @@
+package diagram
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Entity relationship diagram syntax
+var (
+	entityName         = `[\p{L}\p{N}_-]+|"[^"]*"`
+	entityRelationship = regexp.MustCompile(`^(` + entityName + `)\s*(\|o|\|\||\}o|\}\|)(--|\.\.)(o\||\|\||o\{|\|\{)\s*(` +
+		entityName + `)\s*:\s*(.*)$`)
+	entityBlock     = regexp.MustCompile(`^(` + entityName + `)\s*(?:\[\s*"?([^"\]]*)"?\s*\])?\s*(\{)?\s*(\})?$`)
+	entityAttribute = regexp.MustCompile(`^(\S+)\s+(\S+)((?:\s+(?:PK|FK|UK)(?:\s*,\s*(?:PK|FK|UK))*)?)(?:\s+"[^"]*")?$`)
+)
+
+// Ends of relationships, as the markers drawn for their cardinality
+var entityMarkers = map[string]string{
+	"|o": markerZeroOne, "o|": markerZeroOne, "||": markerOne, "}o": markerZeroMany, "o{": markerZeroMany,
+	"}|": markerMany, "|{": markerMany,
+}
+
+// parseEntities parses a Mermaid entity relationship diagram: entities with their attributes and keys, and
+// relationships with their cardinalities and labels. Identifying relationships are drawn solid, and others dashed.
+func parseEntities(header string, body []mermaidLine) (*Graph, error) {
+	g := newGraph()
+	attributes := make(map[*Node][]string)
+	entity := func(name string) *Node {
+		name = strings.Trim(name, `"`)
+		n := g.node(name, shapeClass)
+		if _, ok := attributes[n]; !ok {
+			attributes[n] = nil
+		}
+		return n
+	}
+	var open *Node
+	for _, line := range body {
+		stmt := strings.TrimSpace(line.text)
+		if open != nil {
+			if stmt == "}" {
+				open = nil
+			} else if m := entityAttribute.FindStringSubmatch(stmt); m != nil {
+				attributes[open] = append(attributes[open], strings.Join(strings.Fields(m[1]+" "+m[2]+" "+
+					strings.ReplaceAll(m[3], ",", ", ")), " "))
+			} else {
+				return nil, line.errorf("expected an attribute at %q", stmt)
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(stmt, "direction "):
+			direction := strings.TrimSpace(strings.TrimPrefix(stmt, "direction "))
+			g.LeftRight = direction == "LR" || direction == "RL"
+			continue
+		case strings.HasPrefix(stmt, "accTitle") || strings.HasPrefix(stmt, "accDescr") ||
+			strings.HasPrefix(stmt, "style ") || strings.HasPrefix(stmt, "classDef ") || strings.HasPrefix(stmt, "class "):
+			continue
+		}
+		if m := entityRelationship.FindStringSubmatch(stmt); m != nil {
+			e := g.edge(entity(m[1]), entity(m[5]))
+			e.Dashed = m[3] == ".."
+			e.Tail, e.TailArrow = entityMarkers[m[2]], true
+			e.Head, e.HeadArrow = entityMarkers[m[4]], true
+			e.Label = mermaidLabel(m[6])
+			continue
+		}
+		if m := entityBlock.FindStringSubmatch(stmt); m != nil {
+			n := entity(m[1])
+			if m[2] != "" {
+				n.Label = m[2]
+			}
+			if m[3] != "" && m[4] == "" {
+				open = n
+			}
+			continue
+		}
+		return nil, line.errorf("expected an entity or a relationship at %q", stmt)
+	}
+	if open != nil {
+		return nil, fmt.Errorf("mermaid: entity %s not closed", open.ID)
+	}
+	for _, n := range g.Nodes {
+		n.Sections = []int{1}
+		if len(attributes[n]) > 0 {
+			n.Label += "\n" + strings.Join(attributes[n], "\n")
+			n.Sections = append(n.Sections, len(attributes[n]))
+		}
+	}
+	return g, nil
+}
@@ -0,0 +1,137 @@
+package diagram
+
+import (
+	"bytes"
+	"html"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindDiagram is the NodeKind of diagram code blocks.
+var KindDiagram = ast.NewNodeKind("Diagram")
+
+// Diagram is a fenced code block holding a diagram, in the given language.
+type Diagram struct {
+	ast.BaseBlock
+	Language string
+}
+
+// Kind implements ast.Node.
+func (n *Diagram) Kind() ast.NodeKind {
+	return KindDiagram
+}
+
+// IsRaw implements ast.Node.
+func (n *Diagram) IsRaw() bool {
+	return true
+}
+
+// Dump implements ast.Node.
+func (n *Diagram) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Language": n.Language}, nil)
+}
+
+// Languages of the code blocks drawn as diagrams, and the functions drawing them
+var drawers = map[string]func(src string, palette Palette) (string, error){
+	"dot":      drawDOT,
+	"graphviz": drawDOT,
+	"mermaid":  DrawMermaid,
+}
+
+// drawDOT draws a Graphviz graph.
+func drawDOT(src string, palette Palette) (string, error) {
+	g, err := ParseDOT(src)
+	if err != nil {
+		return "", err
+	}
+	return SVG(g, palette), nil
+}
+
+// diagramTransformer replaces fenced code blocks written in a diagram language with Diagram nodes, before the
+// highlighting extension gets to render them as code.
+type diagramTransformer struct{}
+
+// Transform implements parser.ASTTransformer.
+func (t *diagramTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var blocks []*ast.FencedCodeBlock
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if block, ok := n.(*ast.FencedCodeBlock); ok && entering {
+			if _, ok := drawers[string(block.Language(source))]; ok {
+				blocks = append(blocks, block)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, block := range blocks {
+		diagram := &Diagram{Language: string(block.Language(source))}
+		diagram.SetLines(block.Lines())
+		block.Parent().ReplaceChild(block.Parent(), block, diagram)
+	}
+}
+
+// diagramRenderer draws Diagram nodes as inline SVG.
+type diagramRenderer struct {
+	palette Palette
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (r *diagramRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindDiagram, r.renderDiagram)
+}
+
+func (r *diagramRenderer) renderDiagram(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkSkipChildren, nil
+	}
+	node := n.(*Diagram)
+	var src bytes.Buffer
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		src.Write(segment.Value(source))
+	}
+
+	svg, err := drawers[node.Language](src.String(), r.palette)
+	switch {
+	case err == ErrUnsupported:
+		// Drawn in the browser if the server has a Mermaid script, and shown as source otherwise
+		w.WriteString(`<pre class="mermaid">`)
+		w.WriteString(html.EscapeString(src.String()))
+		w.WriteString("</pre>\n")
+	case err != nil:
+		w.WriteString(`<pre class="diagram-error" title="`)
+		w.WriteString(html.EscapeString(err.Error()))
+		w.WriteString(`"><code>`)
+		w.WriteString(html.EscapeString(src.String()))
+		w.WriteString("</code></pre>\n")
+	default:
+		w.WriteString(`<figure class="diagram">`)
+		w.WriteString(svg)
+		w.WriteString("</figure>\n")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// diagramExtension draws diagram code blocks on the server.
+type diagramExtension struct {
+	palette Palette
+}
+
+// New returns the goldmark extension drawing dot, graphviz and mermaid code blocks as inline SVG in the given
+// colours. The types of Mermaid diagrams that DrawMermaid does not support are kept as <pre class="mermaid"> blocks,
+// for the Mermaid script to draw in the browser.
+func New(palette Palette) goldmark.Extender {
+	return &diagramExtension{palette: palette}
+}
+
+// Extend implements goldmark.Extender.
+func (e *diagramExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(&diagramTransformer{}, 100)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&diagramRenderer{palette: e.palette}, 500)))
+}
@@ -0,0 +1,375 @@
+package diagram
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Measurements of Gantt charts, in pixels
+const (
+	ganttWidth    = 640 // width of the time line
+	ganttRow      = 26
+	ganttBar      = 18
+	ganttAxis     = 24 // height of the axis below the tasks
+	ganttMaxTicks = 10
+)
+
+// Gantt chart syntax
+var (
+	ganttDuration = regexp.MustCompile(`^(\d+(?:\.\d+)?)(ms|s|m|h|d|w)$`)
+	ganttAfter    = regexp.MustCompile(`^after\s+(.+)$`)
+	ganttUntil    = regexp.MustCompile(`^until\s+(\S+)$`)
+)
+
+// Statements that only affect styling, interaction or days left out of durations, which are skipped
+var ganttIgnored = []string{"excludes ", "includes ", "todayMarker ", "tickInterval ", "weekday ", "weekend ",
+	"click ", "accTitle", "accDescr", "inclusiveEndDates", "topAxis"}
+
+// Tokens of Mermaid date formats, longest first, and of axis formats, as Go layouts
+var (
+	ganttDateTokens = strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02", "HH", "15", "mm", "04",
+		"ss", "05", "SSS", "000")
+	ganttAxisTokens = strings.NewReplacer("%Y", "2006", "%y", "06", "%m", "01", "%d", "02", "%e", "_2", "%H", "15",
+		"%M", "04", "%S", "05", "%b", "Jan", "%B", "January", "%a", "Mon", "%A", "Monday", "%%", "%")
+)
+
+// Intervals between the ticks of the axis, the shortest first
+var ganttTicks = []time.Duration{time.Hour, 6 * time.Hour, 24 * time.Hour, 2 * 24 * time.Hour, 7 * 24 * time.Hour,
+	14 * 24 * time.Hour, 28 * 24 * time.Hour, 91 * 24 * time.Hour, 365 * 24 * time.Hour}
+
+// ganttTask is a task or milestone of a Gantt chart, with the start and end written for it until they are resolved.
+type ganttTask struct {
+	label   string
+	section int
+	id      string
+	done    bool
+	active  bool
+	crit    bool
+	mile    bool
+
+	start, end string
+	from, to   time.Time
+	resolved   bool
+}
+
+// gantt is a parsed Gantt chart.
+type gantt struct {
+	title    string
+	layout   string
+	axis     string
+	sections []string
+	tasks    []*ganttTask
+	ids      map[string]*ganttTask
+}
+
+// drawGantt draws a Mermaid Gantt chart: tasks as bars along a time line, grouped in sections, with done, active and
+// critical tasks told apart, milestones as diamonds, and an axis of dates below.
+func drawGantt(header string, body []mermaidLine, palette Palette) (string, error) {
+	g := &gantt{layout: "2006-01-02", axis: "2006-01-02", ids: make(map[string]*ganttTask)}
+	for _, line := range body {
+		if err := g.statement(line, strings.TrimSpace(line.text)); err != nil {
+			return "", err
+		}
+	}
+	if len(g.tasks) == 0 {
+		return "", errors.New("mermaid: Gantt chart without tasks")
+	}
+	for i, t := range g.tasks {
+		if err := g.resolve(t, i, nil); err != nil {
+			return "", err
+		}
+	}
+	return g.draw(palette), nil
+}
+
+// statement parses a single statement.
+func (g *gantt) statement(line mermaidLine, stmt string) error {
+	for _, prefix := range ganttIgnored {
+		if strings.HasPrefix(stmt, prefix) {
+			return nil
+		}
+	}
+	switch {
+	case strings.HasPrefix(stmt, "title "):
+		g.title = strings.TrimSpace(strings.TrimPrefix(stmt, "title "))
+		return nil
+	case strings.HasPrefix(stmt, "dateFormat "):
+		g.layout = ganttDateTokens.Replace(strings.TrimSpace(strings.TrimPrefix(stmt, "dateFormat ")))
+		return nil
+	case strings.HasPrefix(stmt, "axisFormat "):
+		g.axis = ganttAxisTokens.Replace(strings.TrimSpace(strings.TrimPrefix(stmt, "axisFormat ")))
+		return nil
+	case strings.HasPrefix(stmt, "section "):
+		g.sections = append(g.sections, strings.TrimSpace(strings.TrimPrefix(stmt, "section ")))
+		return nil
+	}
+	i := strings.Index(stmt, ":")
+	if i < 0 {
+		return line.errorf("expected a task at %q", stmt)
+	}
+	t := &ganttTask{label: mermaidLabel(strings.TrimSpace(stmt[:i])), section: len(g.sections) - 1}
+	var fields []string
+	for _, f := range strings.Split(stmt[i+1:], ",") {
+		fields = append(fields, strings.TrimSpace(f))
+	}
+	// Tags come first, then the ID, start and end, of which the ID and start may be left out
+tags:
+	for len(fields) > 0 {
+		switch fields[0] {
+		case "done":
+			t.done = true
+		case "active":
+			t.active = true
+		case "crit":
+			t.crit = true
+		case "milestone":
+			t.mile = true
+		default:
+			break tags
+		}
+		fields = fields[1:]
+	}
+	switch len(fields) {
+	case 1:
+		t.end = fields[0]
+	case 2:
+		t.start, t.end = fields[0], fields[1]
+	case 3:
+		t.id, t.start, t.end = fields[0], fields[1], fields[2]
+	default:
+		return line.errorf("expected the start and end of %q", t.label)
+	}
+	if t.id != "" {
+		g.ids[t.id] = t
+	}
+	g.tasks = append(g.tasks, t)
+	return nil
+}
+
+// resolve sets the start and end times of the task at index i, after the tasks it depends on. Tasks without a start
+// follow the task before them. The tasks being resolved are passed along to report cycles.
+func (g *gantt) resolve(t *ganttTask, i int, visiting map[*ganttTask]bool) error {
+	if t.resolved {
+		return nil
+	}
+	if visiting[t] {
+		return fmt.Errorf("mermaid: tasks of %q depend on each other", t.label)
+	}
+	if visiting == nil {
+		visiting = make(map[*ganttTask]bool)
+	}
+	visiting[t] = true
+	dependency := func(id string) (*ganttTask, error) {
+		d, ok := g.ids[id]
+		if !ok {
+			return nil, fmt.Errorf("mermaid: %q depends on unknown task %q", t.label, id)
+		}
+		return d, g.resolve(d, g.index(d), visiting)
+	}
+
+	switch m := ganttAfter.FindStringSubmatch(t.start); {
+	case t.start == "" && i == 0:
+		return fmt.Errorf("mermaid: first task %q without a start", t.label)
+	case t.start == "":
+		prev := g.tasks[i-1]
+		if err := g.resolve(prev, i-1, visiting); err != nil {
+			return err
+		}
+		t.from = prev.to
+	case m != nil:
+		for _, id := range strings.Fields(m[1]) {
+			d, err := dependency(id)
+			if err != nil {
+				return err
+			}
+			if d.to.After(t.from) {
+				t.from = d.to
+			}
+		}
+	default:
+		from, err := time.Parse(g.layout, t.start)
+		if err != nil {
+			return fmt.Errorf("mermaid: start of %q: %v", t.label, err)
+		}
+		t.from = from
+	}
+
+	if m := ganttDuration.FindStringSubmatch(t.end); m != nil {
+		n, _ := strconv.ParseFloat(m[1], 64)
+		unit := map[string]time.Duration{"ms": time.Millisecond, "s": time.Second, "m": time.Minute, "h": time.Hour,
+			"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}[m[2]]
+		t.to = t.from.Add(time.Duration(n * float64(unit)))
+	} else if m := ganttUntil.FindStringSubmatch(t.end); m != nil {
+		d, err := dependency(m[1])
+		if err != nil {
+			return err
+		}
+		t.to = d.from
+	} else {
+		to, err := time.Parse(g.layout, t.end)
+		if err != nil {
+			return fmt.Errorf("mermaid: end of %q: %v", t.label, err)
+		}
+		t.to = to
+	}
+	if t.mile {
+		t.to = t.from
+	}
+	if t.to.Before(t.from) {
+		return fmt.Errorf("mermaid: %q ends before it starts", t.label)
+	}
+	t.resolved = true
+	delete(visiting, t)
+	return nil
+}
+
+// index returns the position of a task in the chart.
+func (g *gantt) index(t *ganttTask) int {
+	for i, other := range g.tasks {
+		if other == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// draw lays out the chart and draws it.
+func (g *gantt) draw(palette Palette) string {
+	first, last := g.tasks[0].from, g.tasks[0].to
+	for _, t := range g.tasks {
+		if t.from.Before(first) {
+			first = t.from
+		}
+		if t.to.After(last) {
+			last = t.to
+		}
+	}
+	span := last.Sub(first)
+	if span <= 0 {
+		span = 24 * time.Hour
+	}
+
+	labelWidth := 0.0
+	for _, s := range g.sections {
+		w, _ := labelSize(s)
+		labelWidth = math.Max(labelWidth, w+2*padX)
+	}
+	left := margin + labelWidth
+	x := func(t time.Time) float64 {
+		return left + float64(t.Sub(first))/float64(span)*ganttWidth
+	}
+	top := float64(margin)
+	if g.title != "" {
+		top += titleHeight
+	}
+	right := left + ganttWidth
+	for _, t := range g.tasks {
+		// Labels that do not fit in their bar are drawn after it
+		w, _ := labelSize(t.label)
+		if w+2*padX > x(t.to)-x(t.from) {
+			right = math.Max(right, x(t.to)+ganttBar/2+padX/2+w)
+		}
+	}
+	bottom := top + float64(len(g.tasks))*ganttRow
+	width, height := right+margin, bottom+ganttAxis+margin
+
+	var b strings.Builder
+	openSVG(&b, width, height)
+	if g.title != "" {
+		writeLabel(&b, g.title, width/2, margin+titleHeight/2, palette.Text, "middle")
+	}
+	g.writeSections(&b, top, width, palette)
+	g.writeAxis(&b, first, span, x, top, bottom, palette)
+	for i, t := range g.tasks {
+		g.writeTask(&b, t, x, top+float64(i)*ganttRow+ganttRow/2, palette)
+	}
+	b.WriteString("</svg>")
+	return b.String()
+}
+
+// writeSections draws the sections as bands behind their tasks, every other one shaded, with their names on the left.
+func (g *gantt) writeSections(b *strings.Builder, top, width float64, palette Palette) {
+	for s, name := range g.sections {
+		first, count := -1, 0
+		for i, t := range g.tasks {
+			if t.section == s {
+				if first < 0 {
+					first = i
+				}
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		y := top + float64(first)*ganttRow
+		if s%2 == 0 {
+			fmt.Fprintf(b, `<rect x="%d" y="%.1f" width="%.1f" height="%d" fill="%s"/>`, margin, y, width-2*margin,
+				count*ganttRow, palette.Fill)
+			b.WriteByte('\n')
+		}
+		writeLabel(b, name, margin+padX/2, y+float64(count*ganttRow)/2, palette.Text, "start")
+	}
+}
+
+// writeAxis draws the grid lines and dates of the axis, at an interval leaving room for their labels.
+func (g *gantt) writeAxis(b *strings.Builder, first time.Time, span time.Duration, x func(time.Time) float64, top,
+	bottom float64, palette Palette) {
+	w, _ := labelSize(first.Format(g.axis))
+	most := math.Min(ganttMaxTicks, ganttWidth/(w+padX))
+	interval := ganttTicks[len(ganttTicks)-1]
+	for _, d := range ganttTicks {
+		if float64(span/d) <= most {
+			interval = d
+			break
+		}
+	}
+	fmt.Fprintf(b, `<path d="M%.1f,%.1f H%.1f" stroke="%s" stroke-width="1"/>`, x(first), bottom,
+		x(first)+ganttWidth, palette.Stroke)
+	b.WriteByte('\n')
+	for t := first; !t.After(first.Add(span)); t = t.Add(interval) {
+		fmt.Fprintf(b, `<path d="M%.1f,%.1f V%.1f" stroke="%s" stroke-width="1" stroke-dasharray="2,3"/>`, x(t), top,
+			bottom+4, palette.Stroke)
+		b.WriteByte('\n')
+		writeLabel(b, t.Format(g.axis), x(t), bottom+ganttAxis/2+2, palette.Text, "middle")
+	}
+}
+
+// writeTask draws a task as a bar with its label inside, or after it if it does not fit, and a milestone as a diamond.
+func (g *gantt) writeTask(b *strings.Builder, t *ganttTask, x func(time.Time) float64, y float64, palette Palette) {
+	fill, stroke := palette.Series[0], palette.Stroke
+	if t.section > 0 {
+		fill = palette.Series[t.section%len(palette.Series)]
+	}
+	switch {
+	case t.done:
+		fill = palette.Fill
+	case t.active:
+		stroke = palette.Text
+	}
+	if t.crit {
+		stroke = palette.Accent
+	}
+	from, to := x(t.from), x(t.to)
+	if t.mile {
+		r := ganttBar / 2.0
+		fmt.Fprintf(b, `<polygon points="%.1f,%.1f %.1f,%.1f %.1f,%.1f %.1f,%.1f" fill="%s" stroke="%s" `+
+			`stroke-width="1.5"/>`, from, y-r, from+r, y, from, y+r, from-r, y, fill, stroke)
+		b.WriteByte('\n')
+		writeLabel(b, t.label, from+r+padX/2, y, palette.Text, "start")
+		return
+	}
+	fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%d" rx="3" fill="%s" stroke="%s" stroke-width="1.5"/>`,
+		from, y-ganttBar/2, math.Max(to-from, 1), ganttBar, fill, stroke)
+	b.WriteByte('\n')
+	if w, _ := labelSize(t.label); w+2*padX <= to-from {
+		writeLabel(b, t.label, (from+to)/2, y, palette.Text, "middle")
+	} else {
+		writeLabel(b, t.label, to+padX/2, y, palette.Text, "start")
+	}
+}
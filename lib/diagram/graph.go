@@ -0,0 +1,119 @@
+package diagram
+
+import "strings"
+
+// Node shapes understood by the layout and the SVG writer
+const (
+	shapeBox       = "box"
+	shapeRounded   = "rounded"
+	shapeStadium   = "stadium"
+	shapeEllipse   = "ellipse"
+	shapeCircle    = "circle"
+	shapeDiamond   = "diamond"
+	shapeHexagon   = "hexagon"
+	shapePlaintext = "plaintext"
+	shapeClass     = "class"
+	shapeStart     = "start"
+	shapeEnd       = "end"
+	shapeBar       = "bar"
+)
+
+// Markers drawn at the ends of edges: arrowheads, the diamonds and triangles of class diagrams and the crow's feet of
+// entity relationship diagrams
+const (
+	markerArrow    = ""
+	markerTriangle = "triangle"
+	markerDiamond  = "diamond"
+	markerODiamond = "odiamond"
+	markerOne      = "one"
+	markerZeroOne  = "zeroone"
+	markerMany     = "many"
+	markerZeroMany = "zeromany"
+)
+
+// Graph is a diagram to lay out: labelled nodes connected by edges, ranked from top to bottom, or from left to right.
+type Graph struct {
+	LeftRight bool
+	Nodes     []*Node
+	Edges     []*Edge
+	index     map[string]*Node
+}
+
+// Node is a box, ellipse or other shape with a possibly multi-line label. The label of a class is split into sections
+// of the given numbers of lines, for its name, attributes and methods.
+type Node struct {
+	ID       string
+	Label    string
+	Shape    string
+	Fill     string
+	Stroke   string
+	Dashed   bool
+	Sections []int
+
+	// Layout, filled in by layout
+	x, y, w, h float64
+	rank       int
+	order      float64
+	dummy      bool
+	up, down   []*Node
+}
+
+// Edge connects two nodes, with arrowheads at either end. Head and Tail are the markers used for them, filled
+// arrowheads unless set.
+type Edge struct {
+	From, To  *Node
+	Label     string
+	Color     string
+	Dashed    bool
+	Bold      bool
+	HeadArrow bool
+	TailArrow bool
+	Head      string
+	Tail      string
+
+	// Layout, filled in by layout
+	reversed bool
+	chain    []*Node
+	points   []point
+}
+
+// point is a position in the drawing.
+type point struct {
+	x, y float64
+}
+
+// newGraph creates an empty graph.
+func newGraph() *Graph {
+	return &Graph{index: make(map[string]*Node)}
+}
+
+// node returns the node with the given ID, creating it with the given shape if it does not exist yet.
+func (g *Graph) node(id, shape string) *Node {
+	if n, ok := g.index[id]; ok {
+		return n
+	}
+	n := &Node{ID: id, Label: id, Shape: shape}
+	g.index[id] = n
+	g.Nodes = append(g.Nodes, n)
+	return n
+}
+
+// edge adds an edge between two nodes.
+func (g *Graph) edge(from, to *Node) *Edge {
+	e := &Edge{From: from, To: to}
+	g.Edges = append(g.Edges, e)
+	return e
+}
+
+// ends returns the endpoints of an edge in rank order, which are swapped for edges reversed to break cycles.
+func (e *Edge) ends() (*Node, *Node) {
+	if e.reversed {
+		return e.To, e.From
+	}
+	return e.From, e.To
+}
+
+// lines splits a label into its lines.
+func lines(label string) []string {
+	return strings.Split(label, "\n")
+}
@@ -0,0 +1,473 @@
+package diagram
+
+import (
+	"math"
+	"sort"
+	"unicode/utf8"
+)
+
+// Measurements of the drawing, in pixels
+const (
+	fontSize   = 14
+	charWidth  = 7.8 // average advance of a sans-serif character at fontSize
+	lineHeight = 18
+	padX       = 12
+	padY       = 8
+	nodeSep    = 28
+	rankSep    = 48
+	dummySize  = 10
+	margin     = 8
+	loopSize   = 24
+	startSize  = 14 // diameter of the start of a state diagram
+	barLength  = 70 // size of the bars of forks and joins
+	barWidth   = 8
+)
+
+// Number of passes over the ranks when ordering and placing nodes
+const (
+	orderSweeps = 12
+	placeSweeps = 8
+)
+
+// layout positions the nodes of g and routes its edges, as a layered drawing: nodes are assigned to ranks so that
+// edges point down (or right), ordered within their rank to reduce crossings, and then placed close to their
+// neighbours. It returns the size of the drawing.
+func layout(g *Graph) (width, height float64) {
+	if len(g.Nodes) == 0 {
+		return 2 * margin, 2 * margin
+	}
+	sizeNodes(g)
+	breakCycles(g)
+	rankNodes(g)
+	layers := splitEdges(g)
+	orderLayers(layers)
+	width, height = place(g, layers)
+	route(g)
+	return width, height
+}
+
+// sizeNodes sets the size of every node to fit its label and shape.
+func sizeNodes(g *Graph) {
+	for _, n := range g.Nodes {
+		w, h := labelSize(n.Label)
+		w, h = w+2*padX, h+2*padY
+		switch n.Shape {
+		case shapeEllipse:
+			w, h = w*1.3, h*1.3
+		case shapeCircle:
+			d := math.Max(w, h)
+			w, h = d, d
+		case shapeDiamond:
+			w, h = w*1.6, h*1.6
+		case shapeHexagon, shapeStadium:
+			w += h / 2
+		case shapePlaintext:
+			w, h = w-padX, h-padY
+		case shapeClass:
+			h = float64(len(n.Sections)) * padY
+			for _, count := range n.Sections {
+				h += float64(count) * lineHeight
+			}
+		case shapeStart:
+			w, h = startSize, startSize
+		case shapeEnd:
+			w, h = startSize+4, startSize+4
+		case shapeBar:
+			w, h = barLength, barWidth
+			if g.LeftRight {
+				w, h = h, w
+			}
+		}
+		n.w, n.h = w, h
+	}
+}
+
+// labelSize estimates the size of the text of a label.
+func labelSize(label string) (width, height float64) {
+	longest := 0
+	labelLines := lines(label)
+	for _, l := range labelLines {
+		if c := utf8.RuneCountInString(l); c > longest {
+			longest = c
+		}
+	}
+	return float64(longest) * charWidth, float64(len(labelLines)) * lineHeight
+}
+
+// breakCycles reverses the edges closing a cycle, found by depth-first search, so that every edge can point down.
+func breakCycles(g *Graph) {
+	out := make(map[*Node][]*Edge)
+	for _, e := range g.Edges {
+		if e.From != e.To {
+			out[e.From] = append(out[e.From], e)
+		}
+	}
+	const (
+		unvisited = iota
+		active
+		finished
+	)
+	state := make(map[*Node]int)
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		state[n] = active
+		for _, e := range out[n] {
+			switch state[e.To] {
+			case active:
+				e.reversed = true
+			case unvisited:
+				visit(e.To)
+			}
+		}
+		state[n] = finished
+	}
+	for _, n := range g.Nodes {
+		if state[n] == unvisited {
+			visit(n)
+		}
+	}
+}
+
+// rankNodes assigns every node the length of the longest path reaching it, and then moves nodes with successors down
+// next to them, so that sources do not end up far above what they point to.
+func rankNodes(g *Graph) {
+	succ := make(map[*Node][]*Node)
+	indegree := make(map[*Node]int)
+	for _, e := range g.Edges {
+		if e.From == e.To {
+			continue
+		}
+		u, v := e.ends()
+		succ[u] = append(succ[u], v)
+		indegree[v]++
+	}
+
+	var sorted []*Node
+	for _, n := range g.Nodes {
+		if indegree[n] == 0 {
+			sorted = append(sorted, n)
+		}
+	}
+	for i := 0; i < len(sorted); i++ {
+		n := sorted[i]
+		for _, v := range succ[n] {
+			if n.rank+1 > v.rank {
+				v.rank = n.rank + 1
+			}
+			if indegree[v]--; indegree[v] == 0 {
+				sorted = append(sorted, v)
+			}
+		}
+	}
+
+	for i := len(sorted) - 1; i >= 0; i-- {
+		n := sorted[i]
+		if len(succ[n]) == 0 {
+			continue
+		}
+		closest := math.MaxInt32
+		for _, v := range succ[n] {
+			if v.rank < closest {
+				closest = v.rank
+			}
+		}
+		if closest-1 > n.rank {
+			n.rank = closest - 1
+		}
+	}
+}
+
+// splitEdges groups the nodes by rank, adding a dummy node on every rank crossed by an edge longer than one rank, and
+// links the nodes of consecutive ranks.
+func splitEdges(g *Graph) [][]*Node {
+	ranks := 0
+	for _, n := range g.Nodes {
+		if n.rank+1 > ranks {
+			ranks = n.rank + 1
+		}
+	}
+	layers := make([][]*Node, ranks)
+	for _, n := range g.Nodes {
+		layers[n.rank] = append(layers[n.rank], n)
+	}
+
+	for _, e := range g.Edges {
+		if e.From == e.To {
+			continue
+		}
+		u, v := e.ends()
+		chain := []*Node{u}
+		for r := u.rank + 1; r < v.rank; r++ {
+			d := &Node{dummy: true, rank: r, w: dummySize, h: dummySize}
+			layers[r] = append(layers[r], d)
+			chain = append(chain, d)
+		}
+		chain = append(chain, v)
+		for i := 0; i+1 < len(chain); i++ {
+			chain[i].down = append(chain[i].down, chain[i+1])
+			chain[i+1].up = append(chain[i+1].up, chain[i])
+		}
+		e.chain = chain
+	}
+	return layers
+}
+
+// orderLayers orders the nodes within each rank by the average position of their neighbours, sweeping down and up
+// the ranks, and keeps the order with the fewest crossings.
+func orderLayers(layers [][]*Node) {
+	for _, layer := range layers {
+		numberLayer(layer)
+	}
+	best := copyLayers(layers)
+	fewest := crossings(layers)
+	for sweep := 0; sweep < orderSweeps && fewest > 0; sweep++ {
+		if sweep%2 == 0 {
+			for r := 1; r < len(layers); r++ {
+				sortByBarycenter(layers[r], func(n *Node) []*Node { return n.up })
+			}
+		} else {
+			for r := len(layers) - 2; r >= 0; r-- {
+				sortByBarycenter(layers[r], func(n *Node) []*Node { return n.down })
+			}
+		}
+		if c := crossings(layers); c < fewest {
+			best, fewest = copyLayers(layers), c
+		}
+	}
+	for r := range layers {
+		copy(layers[r], best[r])
+		numberLayer(layers[r])
+	}
+}
+
+// numberLayer records the position of each node within its rank.
+func numberLayer(layer []*Node) {
+	for i, n := range layer {
+		n.order = float64(i)
+	}
+}
+
+// copyLayers returns a copy of the order of every rank.
+func copyLayers(layers [][]*Node) [][]*Node {
+	c := make([][]*Node, len(layers))
+	for r, layer := range layers {
+		c[r] = append([]*Node(nil), layer...)
+	}
+	return c
+}
+
+// sortByBarycenter orders a rank by the average position of the given neighbours of each node. Nodes without such
+// neighbours keep their position.
+func sortByBarycenter(layer []*Node, neighbours func(n *Node) []*Node) {
+	barycenter := make(map[*Node]float64, len(layer))
+	for _, n := range layer {
+		nb := neighbours(n)
+		if len(nb) == 0 {
+			barycenter[n] = n.order
+			continue
+		}
+		sum := 0.0
+		for _, m := range nb {
+			sum += m.order
+		}
+		barycenter[n] = sum / float64(len(nb))
+	}
+	sort.SliceStable(layer, func(i, j int) bool { return barycenter[layer[i]] < barycenter[layer[j]] })
+	numberLayer(layer)
+}
+
+// crossings counts the pairs of links between consecutive ranks that cross.
+func crossings(layers [][]*Node) int {
+	count := 0
+	for r := 0; r+1 < len(layers); r++ {
+		var links [][2]float64
+		for _, n := range layers[r] {
+			for _, m := range n.down {
+				links = append(links, [2]float64{n.order, m.order})
+			}
+		}
+		for i := range links {
+			for j := i + 1; j < len(links); j++ {
+				a, b := links[i], links[j]
+				if (a[0]-b[0])*(a[1]-b[1]) < 0 {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+// place sets the coordinates of the nodes: ranks are stacked, and each node is pulled toward its neighbours on the
+// adjacent ranks while keeping the order and spacing within its rank. It returns the size of the drawing.
+func place(g *Graph, layers [][]*Node) (width, height float64) {
+	// Sizes along the rank and across it
+	along := func(n *Node) float64 {
+		if g.LeftRight {
+			return n.h
+		}
+		return n.w
+	}
+	across := func(n *Node) float64 {
+		if g.LeftRight {
+			return n.w
+		}
+		return n.h
+	}
+
+	// Ranks are spread apart so that edge labels fit between them
+	sep := float64(rankSep)
+	for _, e := range g.Edges {
+		if e.Label == "" {
+			continue
+		}
+		w, h := labelSize(e.Label)
+		if g.LeftRight {
+			sep = math.Max(sep, w+2*padX)
+		} else {
+			sep = math.Max(sep, h+2*padY)
+		}
+	}
+
+	rankPos := make([]float64, len(layers))
+	v := float64(margin)
+	for r, layer := range layers {
+		thickness := 0.0
+		for _, n := range layer {
+			thickness = math.Max(thickness, across(n))
+		}
+		rankPos[r] = v + thickness/2
+		v += thickness + sep
+	}
+	extentAcross := v - sep + margin
+
+	pos := make(map[*Node]float64)
+	for _, layer := range layers {
+		u := 0.0
+		for _, n := range layer {
+			pos[n] = u + along(n)/2
+			u += along(n) + nodeSep
+		}
+	}
+
+	for sweep := 0; sweep < placeSweeps; sweep++ {
+		down := sweep%2 == 0
+		for i := range layers {
+			r := i
+			if !down {
+				r = len(layers) - 1 - i
+			}
+			layer := layers[r]
+			desired := make([]float64, len(layer))
+			for k, n := range layer {
+				nb := n.up
+				if !down {
+					nb = n.down
+				}
+				if len(nb) == 0 {
+					nb = append(append([]*Node(nil), n.up...), n.down...)
+				}
+				desired[k] = pos[n]
+				if len(nb) > 0 {
+					sum := 0.0
+					for _, m := range nb {
+						sum += pos[m]
+					}
+					desired[k] = sum / float64(len(nb))
+				}
+			}
+			// Averaging the positions packed from the left and from the right keeps the spacing
+			gap := func(k int) float64 { return (along(layer[k-1])+along(layer[k]))/2 + nodeSep }
+			left := make([]float64, len(layer))
+			right := make([]float64, len(layer))
+			for k := range layer {
+				left[k] = desired[k]
+				if k > 0 {
+					left[k] = math.Max(desired[k], left[k-1]+gap(k))
+				}
+			}
+			for k := len(layer) - 1; k >= 0; k-- {
+				right[k] = desired[k]
+				if k < len(layer)-1 {
+					right[k] = math.Min(desired[k], right[k+1]-gap(k+1))
+				}
+			}
+			for k, n := range layer {
+				pos[n] = (left[k] + right[k]) / 2
+			}
+		}
+	}
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for n, u := range pos {
+		low = math.Min(low, u-along(n)/2)
+		high = math.Max(high, u+along(n)/2)
+	}
+	extentAlong := high - low + 2*margin
+	for _, layer := range layers {
+		for _, n := range layer {
+			u := pos[n] - low + margin
+			if g.LeftRight {
+				n.x, n.y = rankPos[n.rank], u
+			} else {
+				n.x, n.y = u, rankPos[n.rank]
+			}
+		}
+	}
+
+	// Self-loops stick out to the right of their node
+	loops := 0.0
+	for _, e := range g.Edges {
+		if e.From == e.To {
+			loops = loopSize
+		}
+	}
+	if g.LeftRight {
+		return extentAcross + loops, extentAlong
+	}
+	return extentAlong + loops, extentAcross
+}
+
+// route sets the points of every edge, from the border of its tail through its dummy nodes to the border of its head.
+func route(g *Graph) {
+	for _, e := range g.Edges {
+		if e.From == e.To {
+			n := e.From
+			x, y := n.x+n.w/2, n.y
+			e.points = []point{{x - 2, y - n.h/4}, {x + loopSize, y - n.h/2}, {x + loopSize, y + n.h/2}, {x - 2, y + n.h/4}}
+			continue
+		}
+		pts := make([]point, len(e.chain))
+		for i, n := range e.chain {
+			pts[i] = point{n.x, n.y}
+		}
+		last := len(pts) - 1
+		pts[0] = border(e.chain[0], pts[1])
+		pts[last] = border(e.chain[last], pts[last-1])
+		if e.reversed {
+			for i, j := 0, last; i < j; i, j = i+1, j-1 {
+				pts[i], pts[j] = pts[j], pts[i]
+			}
+		}
+		e.points = pts
+	}
+}
+
+// border returns where the line from the centre of n toward p leaves the shape of n.
+func border(n *Node, p point) point {
+	dx, dy := p.x-n.x, p.y-n.y
+	if n.dummy || dx == 0 && dy == 0 {
+		return point{n.x, n.y}
+	}
+	hw, hh := n.w/2, n.h/2
+	var t float64
+	switch n.Shape {
+	case shapeEllipse, shapeCircle, shapeStart, shapeEnd:
+		t = 1 / math.Hypot(dx/hw, dy/hh)
+	case shapeDiamond:
+		t = 1 / (math.Abs(dx)/hw + math.Abs(dy)/hh)
+	default:
+		t = math.Min(hw/math.Abs(dx), hh/math.Abs(dy))
+	}
+	return point{n.x + t*dx, n.y + t*dy}
+}
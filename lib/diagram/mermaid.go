@@ -0,0 +1,257 @@
+package diagram
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrUnsupported is returned for the types of Mermaid diagrams that are not drawn on the server.
+var ErrUnsupported = errors.New("mermaid: diagram type not supported")
+
+// Types of Mermaid diagrams other than flowcharts, by the keyword they start with, and the functions drawing them
+var mermaidDrawers = map[string]func(header string, body []mermaidLine, palette Palette) (string, error){
+	"sequenceDiagram": drawSequence,
+	"stateDiagram":    drawGraph(parseState),
+	"stateDiagram-v2": drawGraph(parseState),
+	"classDiagram":    drawGraph(parseClasses),
+	"classDiagram-v2": drawGraph(parseClasses),
+	"erDiagram":       drawGraph(parseEntities),
+	"mindmap":         drawGraph(parseMindmap),
+	"pie":             drawPie,
+	"gantt":           drawGantt,
+}
+
+// mermaidLine is a statement of a Mermaid diagram, with its line number.
+type mermaidLine struct {
+	number int
+	text   string
+}
+
+// errorf returns an error about the statement.
+func (l mermaidLine) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("mermaid: line %d: %s", l.number, fmt.Sprintf(format, args...))
+}
+
+// Mermaid syntax
+var (
+	mermaidHeader = regexp.MustCompile(`^(?:graph|flowchart)(?:\s+(TB|TD|BT|LR|RL))?\s*;?$`)
+	mermaidID     = regexp.MustCompile(`^[\p{L}\p{N}_]+`)
+	// Links with text inside, such as -- text --> or -. text .->
+	mermaidTextLink = regexp.MustCompile(`^\s*(<)?(--|==|-\.)\s*([^-=.>|\s][^>|]*?)\s*(-{2,}|={2,}|\.+-)([>xo])?\s*`)
+	// Links, optionally followed by |text|
+	mermaidLink  = regexp.MustCompile(`^\s*(<)?(-\.+-|-{2,}|={2,}|~{3})([>xo])?\s*(?:\|([^|]*)\|\s*)?`)
+	mermaidBreak = regexp.MustCompile(`(?i)<br\s*/?>`)
+)
+
+// Statements that only affect styling or grouping, which are skipped
+var mermaidIgnored = []string{"subgraph", "end", "classDef", "class ", "style ", "linkStyle", "click ", "direction "}
+
+// Node shape delimiters, longest opening delimiter first
+var mermaidShapes = []struct {
+	open, close, shape string
+}{
+	{"(((", ")))", shapeCircle},
+	{"([", "])", shapeStadium},
+	{"[[", "]]", shapeBox},
+	{"[(", ")]", shapeBox},
+	{"((", "))", shapeCircle},
+	{"{{", "}}", shapeHexagon},
+	{"[/", "/]", shapeBox},
+	{"[/", `\]`, shapeBox},
+	{`[\`, `\]`, shapeBox},
+	{`[\`, "/]", shapeBox},
+	{"[", "]", shapeBox},
+	{"(", ")", shapeRounded},
+	{"{", "}", shapeDiamond},
+	{">", "]", shapeBox},
+}
+
+// DrawMermaid draws a Mermaid diagram as an inline SVG element in the given colours. Flowcharts, sequence, state,
+// class and entity relationship diagrams, mindmaps, pie charts and Gantt charts are supported, and other types return
+// ErrUnsupported.
+func DrawMermaid(src string, palette Palette) (string, error) {
+	header, body := mermaidStatements(src)
+	if fields := strings.Fields(header); len(fields) > 0 {
+		if draw, ok := mermaidDrawers[fields[0]]; ok {
+			return draw(header, body, palette)
+		}
+	}
+	g, err := ParseMermaid(src)
+	if err != nil {
+		return "", err
+	}
+	return SVG(g, palette), nil
+}
+
+// mermaidStatements splits a Mermaid diagram into the line naming its type and the statements after it, leaving out
+// blank lines, comments, directives and front matter. Statements keep their indentation, which mindmaps depend on.
+func mermaidStatements(src string) (string, []mermaidLine) {
+	var header string
+	var body []mermaidLine
+	frontMatter := false
+	for i, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "---" && header == "":
+			frontMatter = !frontMatter
+		case frontMatter || trimmed == "" || strings.HasPrefix(trimmed, "%%"):
+		case header == "":
+			header = trimmed
+		default:
+			body = append(body, mermaidLine{number: i + 1, text: strings.TrimRight(line, " \t\r")})
+		}
+	}
+	return header, body
+}
+
+// drawGraph returns a function drawing the diagrams that parse turns into graphs.
+func drawGraph(parse func(header string, body []mermaidLine) (*Graph, error)) func(string, []mermaidLine,
+	Palette) (string, error) {
+	return func(header string, body []mermaidLine, palette Palette) (string, error) {
+		g, err := parse(header, body)
+		if err != nil {
+			return "", err
+		}
+		return SVG(g, palette), nil
+	}
+}
+
+// ParseMermaid parses a Mermaid flowchart: nodes with their shapes and labels, chains of links, & between nodes, and
+// link text. Subgraphs are flattened into the graph and styling statements are ignored. Other kinds of Mermaid
+// diagrams, which DrawMermaid draws, return ErrUnsupported.
+func ParseMermaid(src string) (*Graph, error) {
+	g := newGraph()
+	header := true
+	for i, line := range strings.Split(src, "\n") {
+		for _, stmt := range strings.Split(line, ";") {
+			stmt = strings.TrimSpace(stmt)
+			if stmt == "" || strings.HasPrefix(stmt, "%%") {
+				continue
+			}
+			if header {
+				m := mermaidHeader.FindStringSubmatch(stmt)
+				if m == nil {
+					return nil, ErrUnsupported
+				}
+				g.LeftRight = m[1] == "LR" || m[1] == "RL"
+				header = false
+				continue
+			}
+			if ignoredMermaid(stmt) {
+				continue
+			}
+			if err := parseMermaidStatement(g, stmt); err != nil {
+				return nil, fmt.Errorf("mermaid: line %d: %v", i+1, err)
+			}
+		}
+	}
+	if header {
+		return nil, ErrUnsupported
+	}
+	return g, nil
+}
+
+// ignoredMermaid reports whether a statement only affects styling or grouping.
+func ignoredMermaid(stmt string) bool {
+	for _, prefix := range mermaidIgnored {
+		if strings.HasPrefix(stmt, prefix) || stmt == strings.TrimSpace(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMermaidStatement parses a node declaration or a chain of links.
+func parseMermaidStatement(g *Graph, stmt string) error {
+	left, rest, err := parseMermaidNodes(g, stmt)
+	if err != nil {
+		return err
+	}
+	for strings.TrimSpace(rest) != "" {
+		var e Edge
+		if m := mermaidTextLink.FindStringSubmatch(rest); m != nil {
+			e = mermaidEdge(m[1], m[2]+m[4], m[5], m[3])
+			rest = rest[len(m[0]):]
+		} else if m := mermaidLink.FindStringSubmatch(rest); m != nil {
+			e = mermaidEdge(m[1], m[2], m[3], m[4])
+			rest = rest[len(m[0]):]
+		} else {
+			return fmt.Errorf("expected a link at %q", strings.TrimSpace(rest))
+		}
+		var right []*Node
+		right, rest, err = parseMermaidNodes(g, rest)
+		if err != nil {
+			return err
+		}
+		for _, from := range left {
+			for _, to := range right {
+				edge := g.edge(from, to)
+				e.From, e.To = from, to
+				*edge = e
+			}
+		}
+		left = right
+	}
+	return nil
+}
+
+// mermaidEdge builds an edge from the parts of a link.
+func mermaidEdge(tail, line, head, text string) Edge {
+	return Edge{
+		Label:     mermaidLabel(text),
+		Dashed:    strings.Contains(line, "."),
+		Bold:      strings.Contains(line, "="),
+		HeadArrow: head != "",
+		TailArrow: tail != "",
+	}
+}
+
+// parseMermaidNodes parses one or more nodes separated by &, returning them and the rest of the statement.
+func parseMermaidNodes(g *Graph, s string) ([]*Node, string, error) {
+	var nodes []*Node
+	for {
+		s = strings.TrimLeft(s, " \t")
+		id := mermaidID.FindString(s)
+		if id == "" {
+			return nil, s, fmt.Errorf("expected a node at %q", s)
+		}
+		s = s[len(id):]
+		n := g.node(id, shapeBox)
+		for _, shape := range mermaidShapes {
+			if !strings.HasPrefix(s, shape.open) {
+				continue
+			}
+			end := strings.Index(s[len(shape.open):], shape.close)
+			if end < 0 {
+				continue
+			}
+			n.Label = mermaidLabel(s[len(shape.open) : len(shape.open)+end])
+			n.Shape = shape.shape
+			s = s[len(shape.open)+end+len(shape.close):]
+			break
+		}
+		if strings.HasPrefix(s, ":::") {
+			// Class name
+			s = s[3:]
+			s = s[len(mermaidID.FindString(s)):]
+		}
+		nodes = append(nodes, n)
+
+		trimmed := strings.TrimLeft(s, " \t")
+		if !strings.HasPrefix(trimmed, "&") {
+			return nodes, s, nil
+		}
+		s = trimmed[1:]
+	}
+}
+
+// mermaidLabel unquotes node and link text, turning <br> tags into line breaks.
+func mermaidLabel(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		text = text[1 : len(text)-1]
+	}
+	return mermaidBreak.ReplaceAllString(text, "\n")
+}
@@ -0,0 +1,122 @@
+package diagram
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Elements that safe mode keeps in inline SVG
+var (
+	svgElement   = regexp.MustCompile(`<([a-z]+)`)
+	safeElements = map[string]bool{"svg": true, "rect": true, "ellipse": true, "polygon": true, "path": true,
+		"text": true, "tspan": true}
+)
+
+func TestDrawMermaid(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string // text that the drawing must contain, or that the error must contain
+		err  bool
+	}{
+		{"flowchart", "graph LR\n  A[Start] --> B{Choice}", []string{">Start<", ">Choice<"}, false},
+		{"sequence", `sequenceDiagram
+			autonumber
+			actor User
+			participant S as Server
+			User->>+S: Request
+			loop Every second
+				S-->>S: Poll
+			end
+			alt found
+				S--)User: Response
+			else missing
+				S-xUser: Error
+			end
+			Note over User,S: Done`,
+			[]string{">User<", ">Server<", ">Request<", ">Poll<", ">loop<", ">[Every second]<", ">[missing]<",
+				">Done<", ">3<"}, false},
+		{"sequence without end", "sequenceDiagram\n  loop forever\n  A->>B: hi", []string{"loop without end"}, true},
+		{"state", `stateDiagram-v2
+			[*] --> Idle
+			Idle --> Running : start
+			state Running {
+				[*] --> Working
+			}
+			Running --> [*]
+			note right of Idle : Waiting`,
+			[]string{">Idle<", ">start<", ">Working<", ">Waiting<"}, false},
+		{"class", `classDiagram
+			Animal <|-- Duck
+			Animal "1" *-- "many" Leg : has
+			class Duck {
+				+String beakColor
+				+swim()
+			}
+			class List~T~`,
+			[]string{">Animal<", ">+String beakColor<", ">+swim()<", ">has<", ">1 : many<", ">List&lt;T&gt;<"}, false},
+		{"entity relationship", `erDiagram
+			CUSTOMER ||--o{ ORDER : places
+			CUSTOMER {
+				string name PK
+			}`,
+			[]string{">CUSTOMER<", ">ORDER<", ">places<", ">string name PK<"}, false},
+		{"mindmap", "mindmap\n  root((Ideas))\n    One\n    Two[Second idea]", []string{">Ideas<", ">One<",
+			">Second idea<"}, false},
+		{"mindmap with two roots", "mindmap\n  One\n  Two", []string{"single root"}, true},
+		{"pie", "pie showData title Pets\n  \"Dogs\" : 3\n  \"Cats\" : 1", []string{">Pets<", ">Dogs [3]<", ">75%<"},
+			false},
+		{"pie without values", "pie\n  \"Dogs\" : 0", []string{"without values"}, true},
+		{"gantt", `gantt
+			title Plan
+			dateFormat YYYY-MM-DD
+			section Design
+			Sketch :done, a1, 2024-01-01, 3d
+			Review :crit, after a1, 2d
+			section Build
+			Code :b1, 2024-01-06, 2024-01-12
+			Release :milestone, after b1, 0d`,
+			[]string{">Plan<", ">Design<", ">Sketch<", ">Review<", ">Release<", Light.Accent}, false},
+		{"gantt with unknown task", "gantt\n  A :after x, 1d", []string{`unknown task "x"`}, true},
+		{"gantt with bad date", "gantt\n  A :2024-13-01, 1d", []string{`start of "A"`}, true},
+	}
+	for _, tt := range tests {
+		svg, err := DrawMermaid(tt.src, Light)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: drawn", tt.name)
+				continue
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("%s: error %q does not mention %q", tt.name, err, want)
+				}
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(svg, want) {
+				t.Errorf("%s: no %q in\n%s", tt.name, want, svg)
+			}
+		}
+		for _, m := range svgElement.FindAllStringSubmatch(svg, -1) {
+			if !safeElements[m[1]] {
+				t.Errorf("%s: <%s> is removed in safe mode", tt.name, m[1])
+			}
+		}
+	}
+}
+
+func TestDrawMermaidUnsupported(t *testing.T) {
+	for _, src := range []string{"gitGraph\n  commit", "journey\n  title Day", "timeline\n  2024 : Start"} {
+		if _, err := DrawMermaid(src, Dark); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%q: got %v, want ErrUnsupported", src, err)
+		}
+	}
+}
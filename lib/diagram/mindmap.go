@@ -0,0 +1,66 @@
+package diagram
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mindmap node shape delimiters, longest opening delimiter first
+var mindmapShapes = []struct {
+	open, close, shape string
+}{
+	{"((", "))", shapeCircle},
+	{"))", "((", shapeCircle},
+	{"{{", "}}", shapeHexagon},
+	{"(", ")", shapeRounded},
+	{")", "(", shapeRounded},
+	{"[", "]", shapeBox},
+}
+
+// parseMindmap parses a Mermaid mindmap, whose indentation nests every idea below the one it belongs to. It is drawn
+// from left to right, with the root first. Icons and classes are ignored.
+func parseMindmap(header string, body []mermaidLine) (*Graph, error) {
+	g := newGraph()
+	g.LeftRight = true
+	type level struct {
+		indent int
+		node   *Node
+	}
+	var parents []level
+	for _, line := range body {
+		stmt := strings.TrimSpace(line.text)
+		if strings.HasPrefix(stmt, "::icon(") || strings.HasPrefix(stmt, ":::") {
+			continue
+		}
+		indent := len(line.text) - len(strings.TrimLeft(line.text, " \t"))
+		n := g.node(fmt.Sprintf("\x00%d", line.number), shapeRounded)
+		n.Label, n.Shape = mindmapNode(stmt)
+		for len(parents) > 0 && parents[len(parents)-1].indent >= indent {
+			parents = parents[:len(parents)-1]
+		}
+		if len(parents) == 0 && len(g.Nodes) > 1 {
+			return nil, line.errorf("mindmaps have a single root, found %q", stmt)
+		}
+		if len(parents) > 0 {
+			g.edge(parents[len(parents)-1].node, n)
+		}
+		parents = append(parents, level{indent, n})
+	}
+	return g, nil
+}
+
+// mindmapNode returns the text and shape of an idea, written as its text alone or as an ID followed by the text
+// between the delimiters of a shape.
+func mindmapNode(stmt string) (string, string) {
+	if i := strings.IndexAny(stmt, "[({)"); i >= 0 && !strings.ContainsAny(stmt[:i], " \t") {
+		rest := strings.TrimSpace(stmt[i:])
+		for _, shape := range mindmapShapes {
+			if len(rest) >= len(shape.open)+len(shape.close) && strings.HasPrefix(rest, shape.open) &&
+				strings.HasSuffix(rest, shape.close) {
+				text := rest[len(shape.open) : len(rest)-len(shape.close)]
+				return mermaidLabel(strings.Trim(strings.TrimSpace(text), "`")), shape.shape
+			}
+		}
+	}
+	return mermaidLabel(strings.Trim(stmt, "`")), shapeRounded
+}
@@ -0,0 +1,127 @@
+package diagram
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Measurements of pie charts, in pixels
+const (
+	pieRadius    = 110
+	legendSquare = 12
+	legendGap    = 24
+	legendRow    = 22
+	titleHeight  = 28
+	// Smallest share of a slice labelled with its percentage
+	pieLabelShare = 0.04
+)
+
+// Pie chart syntax
+var (
+	pieHeader = regexp.MustCompile(`^pie(\s+showData)?(?:\s+title\s+(.*))?$`)
+	pieSlice  = regexp.MustCompile(`^"([^"]*)"\s*:\s*([0-9.eE+-]+)$`)
+)
+
+// slice is a slice of a pie chart.
+type slice struct {
+	label string
+	value float64
+}
+
+// drawPie draws a Mermaid pie chart: the slices in the colours of the palette, starting at the top and going
+// clockwise, labelled with their share, and a legend of their labels, and of their values with showData.
+func drawPie(header string, body []mermaidLine, palette Palette) (string, error) {
+	m := pieHeader.FindStringSubmatch(header)
+	if m == nil {
+		return "", fmt.Errorf("mermaid: unexpected %q", header)
+	}
+	showData, title := m[1] != "", strings.TrimSpace(m[2])
+	var slices []slice
+	total := 0.0
+	for _, line := range body {
+		stmt := strings.TrimSpace(line.text)
+		switch {
+		case strings.HasPrefix(stmt, "title "):
+			title = strings.TrimSpace(strings.TrimPrefix(stmt, "title "))
+		case stmt == "showData":
+			showData = true
+		case strings.HasPrefix(stmt, "accTitle") || strings.HasPrefix(stmt, "accDescr"):
+		default:
+			m := pieSlice.FindStringSubmatch(stmt)
+			if m == nil {
+				return "", line.errorf("expected \"label\" : value at %q", stmt)
+			}
+			value, err := strconv.ParseFloat(m[2], 64)
+			if err != nil || value < 0 || math.IsInf(value, 0) {
+				return "", line.errorf("invalid value %q", m[2])
+			}
+			slices = append(slices, slice{label: m[1], value: value})
+			total += value
+		}
+	}
+	if total <= 0 {
+		return "", errors.New("mermaid: pie chart without values")
+	}
+
+	legend := make([]string, len(slices))
+	legendWidth := 0.0
+	for i, s := range slices {
+		legend[i] = s.label
+		if showData {
+			legend[i] += " [" + strconv.FormatFloat(s.value, 'f', -1, 64) + "]"
+		}
+		w, _ := labelSize(legend[i])
+		legendWidth = math.Max(legendWidth, legendSquare+8+w)
+	}
+	top := float64(margin)
+	if title != "" {
+		top += titleHeight
+	}
+	cx, cy := float64(margin+pieRadius), top+pieRadius
+	width := 2*margin + 2*pieRadius + legendGap + legendWidth
+	height := math.Max(top+2*pieRadius, top+float64(len(slices))*legendRow) + margin
+
+	var b strings.Builder
+	openSVG(&b, width, height)
+	if title != "" {
+		writeLabel(&b, title, width/2, margin+titleHeight/2, palette.Text, "middle")
+	}
+	angle := -math.Pi / 2
+	for i, s := range slices {
+		fill := palette.Series[i%len(palette.Series)]
+		sweep := 2 * math.Pi * s.value / total
+		switch {
+		case s.value == total:
+			fmt.Fprintf(&b, `<ellipse cx="%.1f" cy="%.1f" rx="%d" ry="%d" fill="%s" stroke="%s" stroke-width="1.5"/>`,
+				cx, cy, pieRadius, pieRadius, fill, palette.Background)
+		case s.value > 0:
+			large := 0
+			if sweep > math.Pi {
+				large = 1
+			}
+			x1, y1 := cx+pieRadius*math.Cos(angle), cy+pieRadius*math.Sin(angle)
+			x2, y2 := cx+pieRadius*math.Cos(angle+sweep), cy+pieRadius*math.Sin(angle+sweep)
+			fmt.Fprintf(&b, `<path d="M%.1f,%.1f L%.1f,%.1f A%d,%d 0 %d,1 %.1f,%.1f Z" fill="%s" stroke="%s" `+
+				`stroke-width="1.5"/>`, cx, cy, x1, y1, pieRadius, pieRadius, large, x2, y2, fill, palette.Background)
+		}
+		b.WriteByte('\n')
+		if share := s.value / total; share >= pieLabelShare {
+			mid := angle + sweep/2
+			writeLabel(&b, fmt.Sprintf("%.0f%%", share*100), cx+0.65*pieRadius*math.Cos(mid),
+				cy+0.65*pieRadius*math.Sin(mid), palette.Text, "middle")
+		}
+		angle += sweep
+
+		x, y := float64(margin+2*pieRadius+legendGap), top+float64(i)*legendRow+legendRow/2
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%d" height="%d" fill="%s" stroke="%s" stroke-width="1"/>`,
+			x, y-legendSquare/2, legendSquare, legendSquare, fill, palette.Stroke)
+		b.WriteByte('\n')
+		writeLabel(&b, legend[i], x+legendSquare+8, y, palette.Text, "start")
+	}
+	b.WriteString("</svg>")
+	return b.String(), nil
+}
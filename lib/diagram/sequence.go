@@ -0,0 +1,654 @@
+package diagram
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Measurements of sequence diagrams, in pixels
+const (
+	participantMinWidth = 80
+	participantGap      = 40
+	actorHeight         = 36 // height of the figure drawn for actors
+	messageGap          = 16 // space below each message
+	selfLoopWidth       = 30
+	selfLoopHeight      = 20
+	noteMargin          = 8 // space between a note and the lifeline it is next to
+	activationWidth     = 10
+	blockPadding        = 30 // space around the outermost block, less for every block inside
+)
+
+// Kinds of the events of a sequence diagram
+const (
+	eventMessage = iota
+	eventNote
+	eventStart
+	eventDivider
+	eventEnd
+	eventActivate
+	eventDeactivate
+)
+
+// Sequence diagram syntax
+var (
+	sequenceParticipant = regexp.MustCompile(`^(?:create\s+)?(participant|actor)\s+(\S+?)(?:\s+as\s+(.+))?$`)
+	sequenceMessage     = regexp.MustCompile(`^([^\s:+<>]+?)\s*(<<-->>|<<->>|-->>|->>|-->|->|--x|-x|--\)|-\))\s*([+-])?` +
+		`\s*([^\s:+<>]+?)\s*(?::\s*(.*))?$`)
+	sequenceNote = regexp.MustCompile(`(?i)^note\s+(left of|right of|over)\s+([^:,]+?)\s*(?:,\s*([^:]+?)\s*)?` +
+		`:\s*(.*)$`)
+	sequenceActivation = regexp.MustCompile(`^(activate|deactivate)\s+(\S+)$`)
+	sequenceOpen       = regexp.MustCompile(`^(loop|alt|opt|par|critical|break|rect|box)\b\s*(.*)$`)
+	sequenceDivider    = regexp.MustCompile(`^(else|and|option)\b\s*(.*)$`)
+)
+
+// Statements that only affect styling, links or the life of participants, which are skipped
+var sequenceIgnored = []string{"destroy ", "link ", "links ", "accTitle", "accDescr", "properties "}
+
+// participant is a lifeline of a sequence diagram.
+type participant struct {
+	label string
+	actor bool
+	order int
+
+	// Layout: centre and width
+	x, w float64
+}
+
+// sequenceEvent is a message, note, activation or part of a block, in the order they happen.
+type sequenceEvent struct {
+	kind     int
+	from, to *participant
+	text     string
+
+	// Messages: dashed lines, and the markers at either end; notes: left of, right of or over
+	dashed     bool
+	head, tail string
+	side       string
+
+	// Layout: position of the message or top of the note, and of the ends of the lifelines between activations
+	y       float64
+	offsets [2]float64
+	number  int
+	keyword string
+}
+
+// sequenceBlock is a loop, alternative or other block around a part of the events, divided into sections.
+type sequenceBlock struct {
+	keyword   string
+	labels    []string
+	dividers  []float64
+	top, foot float64
+	depth     int
+	first     *participant
+	last      *participant
+	right     float64
+	hidden    bool
+}
+
+// sequence is a parsed sequence diagram.
+type sequence struct {
+	title        string
+	participants []*participant
+	index        map[string]*participant
+	events       []*sequenceEvent
+	autonumber   bool
+}
+
+// drawSequence draws a Mermaid sequence diagram: participants and actors with their lifelines, messages with their
+// kinds of arrows, optionally numbered, notes, activations and blocks such as loops and alternatives.
+func drawSequence(header string, body []mermaidLine, palette Palette) (string, error) {
+	s, err := parseSequence(body)
+	if err != nil {
+		return "", err
+	}
+	if len(s.participants) == 0 {
+		return "", errors.New("mermaid: sequence diagram without participants")
+	}
+	return s.draw(palette), nil
+}
+
+// parseSequence parses the statements of a sequence diagram.
+func parseSequence(body []mermaidLine) (*sequence, error) {
+	s := &sequence{index: make(map[string]*participant)}
+	var open []string
+	for _, line := range body {
+		stmt := strings.TrimSpace(line.text)
+		if ignoredSequence(stmt) {
+			continue
+		}
+		switch {
+		case stmt == "autonumber" || strings.HasPrefix(stmt, "autonumber "):
+			s.autonumber = !strings.HasSuffix(stmt, " off")
+			continue
+		case strings.HasPrefix(stmt, "title ") || strings.HasPrefix(stmt, "title:"):
+			s.title = strings.TrimSpace(strings.TrimLeft(strings.TrimPrefix(stmt, "title"), ":"))
+			continue
+		case stmt == "end":
+			if len(open) == 0 {
+				return nil, line.errorf("end without a block")
+			}
+			if open[len(open)-1] != "box" {
+				s.events = append(s.events, &sequenceEvent{kind: eventEnd})
+			}
+			open = open[:len(open)-1]
+			continue
+		}
+		if m := sequenceParticipant.FindStringSubmatch(stmt); m != nil {
+			p := s.participant(m[2])
+			p.actor = m[1] == "actor"
+			if m[3] != "" {
+				p.label = mermaidLabel(m[3])
+			}
+			continue
+		}
+		if m := sequenceNote.FindStringSubmatch(stmt); m != nil {
+			e := &sequenceEvent{kind: eventNote, side: strings.ToLower(strings.Fields(m[1])[0]),
+				from: s.participant(m[2]), text: mermaidLabel(m[4])}
+			e.to = e.from
+			if m[3] != "" {
+				e.to = s.participant(m[3])
+			}
+			if e.to.order < e.from.order {
+				e.from, e.to = e.to, e.from
+			}
+			s.events = append(s.events, e)
+			continue
+		}
+		if m := sequenceActivation.FindStringSubmatch(stmt); m != nil {
+			kind := eventActivate
+			if m[1] == "deactivate" {
+				kind = eventDeactivate
+			}
+			s.events = append(s.events, &sequenceEvent{kind: kind, from: s.participant(m[2])})
+			continue
+		}
+		if m := sequenceOpen.FindStringSubmatch(stmt); m != nil {
+			open = append(open, m[1])
+			if m[1] != "box" {
+				s.events = append(s.events, &sequenceEvent{kind: eventStart, keyword: m[1], text: mermaidLabel(m[2])})
+			}
+			continue
+		}
+		if m := sequenceDivider.FindStringSubmatch(stmt); m != nil && len(open) > 0 {
+			s.events = append(s.events, &sequenceEvent{kind: eventDivider, text: mermaidLabel(m[2])})
+			continue
+		}
+		if m := sequenceMessage.FindStringSubmatch(stmt); m != nil {
+			e := &sequenceEvent{kind: eventMessage, from: s.participant(m[1]), to: s.participant(m[4]),
+				text: mermaidLabel(m[5]), dashed: strings.Contains(m[2], "--")}
+			switch strings.TrimLeft(strings.TrimLeft(m[2], "<"), "-") {
+			case ">>":
+				e.head = markerArrow
+				if strings.HasPrefix(m[2], "<<") {
+					e.tail = markerArrow
+				}
+			case "x":
+				e.head = "cross"
+			case ")":
+				e.head = "open"
+			default:
+				e.head = "none"
+			}
+			if e.tail == "" {
+				e.tail = "none"
+			}
+			s.events = append(s.events, e)
+			switch m[3] {
+			case "+":
+				s.events = append(s.events, &sequenceEvent{kind: eventActivate, from: e.to})
+			case "-":
+				s.events = append(s.events, &sequenceEvent{kind: eventDeactivate, from: e.from})
+			}
+			continue
+		}
+		return nil, line.errorf("expected a message at %q", stmt)
+	}
+	if len(open) > 0 {
+		return nil, fmt.Errorf("mermaid: %s without end", open[len(open)-1])
+	}
+	return s, nil
+}
+
+// ignoredSequence reports whether a statement only affects styling, links or the life of participants.
+func ignoredSequence(stmt string) bool {
+	for _, prefix := range sequenceIgnored {
+		if strings.HasPrefix(stmt, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// participant returns the participant with the given name, adding it after the others if it is new.
+func (s *sequence) participant(name string) *participant {
+	if p, ok := s.index[name]; ok {
+		return p
+	}
+	p := &participant{label: name, order: len(s.participants)}
+	s.index[name] = p
+	s.participants = append(s.participants, p)
+	return p
+}
+
+// headHeight returns the height of the boxes and figures showing the participants above and below their lifelines.
+func (s *sequence) headHeight() float64 {
+	height := 0.0
+	for _, p := range s.participants {
+		_, h := labelSize(p.label)
+		if p.actor {
+			h += actorHeight + padY
+		} else {
+			h += 2 * padY
+		}
+		height = math.Max(height, h)
+	}
+	return height
+}
+
+// place sets the position of every participant, far enough apart for the messages and notes between them.
+func (s *sequence) place() {
+	// Smallest distance between the centres of two participants, the first one before the second
+	type constraint struct {
+		from, to *participant
+		distance float64
+	}
+	var constraints []constraint
+	left := 0.0
+	for i, p := range s.participants {
+		w, _ := labelSize(p.label)
+		p.w = math.Max(w+2*padX, participantMinWidth)
+		if i > 0 {
+			prev := s.participants[i-1]
+			constraints = append(constraints, constraint{prev, p, (prev.w+p.w)/2 + participantGap})
+		}
+	}
+	next := func(p *participant) *participant {
+		if p.order+1 < len(s.participants) {
+			return s.participants[p.order+1]
+		}
+		return nil
+	}
+	for _, e := range s.events {
+		w, _ := labelSize(e.text)
+		switch {
+		case e.kind == eventMessage && e.from != e.to:
+			from, to := e.from, e.to
+			if to.order < from.order {
+				from, to = to, from
+			}
+			constraints = append(constraints, constraint{from, to, w + 2*padX + 2*activationWidth})
+		case e.kind == eventMessage:
+			if n := next(e.from); n != nil {
+				constraints = append(constraints, constraint{e.from, n, w + selfLoopWidth + 2*padX})
+			}
+		case e.kind == eventNote && e.side == "right":
+			if n := next(e.from); n != nil {
+				constraints = append(constraints, constraint{e.from, n, w + 2*padX + 2*noteMargin})
+			}
+		case e.kind == eventNote && e.side == "left" && e.from.order > 0:
+			constraints = append(constraints, constraint{s.participants[e.from.order-1], e.from,
+				w + 2*padX + 2*noteMargin})
+		case e.kind == eventNote && e.side == "left":
+			left = math.Max(left, w+2*padX+noteMargin-e.from.w/2)
+		case e.kind == eventNote && e.from != e.to:
+			constraints = append(constraints, constraint{e.from, e.to, w + 2*padX - 2*participantGap})
+		}
+	}
+
+	for _, p := range s.participants {
+		p.x = margin + blockPadding + p.w/2
+		if p.order == 0 {
+			p.x += left
+		}
+		for _, c := range constraints {
+			if c.to == p {
+				p.x = math.Max(p.x, c.from.x+c.distance)
+			}
+		}
+	}
+}
+
+// layOut sets the vertical positions of the events, the ends of activations and the extents of the blocks, and
+// returns the blocks and the position below the last event.
+func (s *sequence) layOut(top float64) ([]*sequenceBlock, float64) {
+	y := top
+	var blocks []*sequenceBlock
+	var open []*sequenceBlock
+	active := make(map[*participant]int)
+	number := 0
+	// include widens the open blocks to the participants from first to last, and to the given right edge
+	include := func(first, last *participant, right float64) {
+		for _, b := range open {
+			if b.first == nil || first.order < b.first.order {
+				b.first = first
+			}
+			if b.last == nil || last.order > b.last.order {
+				b.last = last
+			}
+			b.right = math.Max(b.right, right)
+		}
+	}
+	for _, e := range s.events {
+		_, h := labelSize(e.text)
+		if e.text == "" {
+			h = 0
+		}
+		switch e.kind {
+		case eventMessage:
+			if s.autonumber {
+				number++
+				e.number = number
+			}
+			e.offsets = [2]float64{float64(active[e.from]) * activationWidth / 2,
+				float64(active[e.to]) * activationWidth / 2}
+			y += h + 6
+			e.y = y
+			w, _ := labelSize(e.text)
+			if e.from == e.to {
+				y += selfLoopHeight
+				include(e.from, e.to, e.from.x+selfLoopWidth+w+padX)
+			} else {
+				first, last := e.from, e.to
+				if last.order < first.order {
+					first, last = last, first
+				}
+				include(first, last, 0)
+			}
+			y += messageGap
+		case eventNote:
+			e.y = y
+			y += h + 2*padY + messageGap
+			include(e.from, e.to, s.noteBox(e).right)
+		case eventActivate:
+			active[e.from]++
+			e.y = y - messageGap
+		case eventDeactivate:
+			if active[e.from] > 0 {
+				active[e.from]--
+			}
+			e.y = y - messageGap
+		case eventStart:
+			b := &sequenceBlock{keyword: e.keyword, labels: []string{e.text}, top: y, depth: len(open),
+				hidden: e.keyword == "rect"}
+			blocks = append(blocks, b)
+			open = append(open, b)
+			y += lineHeight + 2*padY
+		case eventDivider:
+			if len(open) > 0 {
+				b := open[len(open)-1]
+				b.dividers = append(b.dividers, y)
+				b.labels = append(b.labels, e.text)
+			}
+			y += lineHeight + 2*padY
+		case eventEnd:
+			if len(open) > 0 {
+				open[len(open)-1].foot = y
+				open = open[:len(open)-1]
+			}
+			y += padY
+		}
+	}
+	return blocks, y
+}
+
+// noteRect is the box of a note.
+type noteRect struct {
+	left, right, width, height float64
+}
+
+// noteBox returns the box of a note, next to or over the lifelines of its participants.
+func (s *sequence) noteBox(e *sequenceEvent) noteRect {
+	w, h := labelSize(e.text)
+	w, h = w+2*padX, h+2*padY
+	switch e.side {
+	case "left":
+		right := e.from.x - noteMargin
+		return noteRect{right - w, right, w, h}
+	case "right":
+		left := e.from.x + noteMargin
+		return noteRect{left, left + w, w, h}
+	}
+	left, right := e.from.x-participantGap/2, e.to.x+participantGap/2
+	if right-left < w {
+		mid := (left + right) / 2
+		left, right = mid-w/2, mid+w/2
+	}
+	return noteRect{left, right, right - left, h}
+}
+
+// draw lays out the diagram and draws it.
+func (s *sequence) draw(palette Palette) string {
+	s.place()
+	top := float64(margin)
+	if s.title != "" {
+		top += titleHeight
+	}
+	head := s.headHeight()
+	blocks, bottom := s.layOut(top + head + messageGap + padY)
+
+	var b strings.Builder
+	width := 0.0
+	for _, p := range s.participants {
+		width = math.Max(width, p.x+p.w/2+blockPadding+margin)
+	}
+	for _, blk := range blocks {
+		width = math.Max(width, blk.right+blockPadding+margin)
+	}
+	for _, e := range s.events {
+		if e.kind == eventNote {
+			width = math.Max(width, s.noteBox(e).right+margin)
+		}
+	}
+	height := bottom + head + margin
+	openSVG(&b, width, height)
+	if s.title != "" {
+		writeLabel(&b, s.title, width/2, margin+titleHeight/2, palette.Text, "middle")
+	}
+
+	// Lifelines behind everything else, then highlighted parts, blocks and activations
+	for _, p := range s.participants {
+		fmt.Fprintf(&b, `<path d="M%.1f,%.1f V%.1f" stroke="%s" stroke-width="1" stroke-dasharray="4,3"/>`,
+			p.x, top+head, bottom, palette.Stroke)
+		b.WriteByte('\n')
+	}
+	for _, blk := range blocks {
+		s.writeBlock(&b, blk, palette)
+	}
+	s.writeActivations(&b, bottom, palette)
+	for _, e := range s.events {
+		switch e.kind {
+		case eventMessage:
+			s.writeMessage(&b, e, palette)
+		case eventNote:
+			box := s.noteBox(e)
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" stroke="%s" stroke-width="1"/>`,
+				box.left, e.y, box.width, box.height, palette.Fill, palette.Stroke)
+			b.WriteByte('\n')
+			writeText(&b, e.text, (box.left+box.right)/2, e.y+box.height/2, palette.Text)
+		}
+	}
+	for _, p := range s.participants {
+		writeParticipant(&b, p, top, head, palette)
+		writeParticipant(&b, p, bottom, head, palette)
+	}
+	b.WriteString("</svg>")
+	return b.String()
+}
+
+// writeParticipant draws a participant as a box, or an actor as a figure above its name.
+func writeParticipant(b *strings.Builder, p *participant, top, height float64, palette Palette) {
+	if !p.actor {
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="3" fill="%s" stroke="%s" `+
+			`stroke-width="1.5"/>`, p.x-p.w/2, top, p.w, height, palette.Fill, palette.Stroke)
+		b.WriteByte('\n')
+		writeText(b, p.label, p.x, top+height/2, palette.Text)
+		return
+	}
+	x, y := p.x, top+2
+	fmt.Fprintf(b, `<ellipse cx="%.1f" cy="%.1f" rx="6" ry="6" fill="%s" stroke="%s" stroke-width="1.5"/>`,
+		x, y+6, palette.Fill, palette.Stroke)
+	b.WriteByte('\n')
+	fmt.Fprintf(b, `<path d="M%.1f,%.1f V%.1f M%.1f,%.1f H%.1f M%.1f,%.1f L%.1f,%.1f M%.1f,%.1f L%.1f,%.1f" `+
+		`fill="none" stroke="%s" stroke-width="1.5"/>`, x, y+12, y+24, x-10, y+16, x+10, x, y+24, x-8, y+actorHeight-2,
+		x, y+24, x+8, y+actorHeight-2, palette.Stroke)
+	b.WriteByte('\n')
+	_, h := labelSize(p.label)
+	writeText(b, p.label, x, top+actorHeight+padY/2+h/2, palette.Text)
+}
+
+// writeMessage draws a message as an arrow between two lifelines, or as a loop back to the same one, with its text
+// above it.
+func (s *sequence) writeMessage(b *strings.Builder, e *sequenceEvent, palette Palette) {
+	dash := ""
+	if e.dashed {
+		dash = ` stroke-dasharray="5,3"`
+	}
+	y := e.y
+	_, h := labelSize(e.text)
+	if e.from == e.to {
+		x := e.from.x + e.offsets[0]
+		end := point{x, y + selfLoopHeight}
+		stop := end
+		var tip string
+		if e.head == markerArrow {
+			tip, stop = marker(markerArrow, point{x + selfLoopWidth, end.y}, end, palette.Stroke, palette.Background)
+		}
+		fmt.Fprintf(b, `<path d="M%.1f,%.1f H%.1f V%.1f H%.1f" fill="none" stroke="%s" stroke-width="1.5"%s/>`,
+			x, y, x+selfLoopWidth, end.y, stop.x, palette.Stroke, dash)
+		b.WriteByte('\n')
+		b.WriteString(tip)
+		s.writeEnd(b, e.head, point{x + selfLoopWidth, end.y}, end, palette)
+		for i, l := range lines(e.text) {
+			writeLabel(b, l, x+selfLoopWidth+padX/2, y-h+float64(i)*lineHeight+lineHeight/2+selfLoopHeight/2,
+				palette.Text, "start")
+		}
+		s.writeNumber(b, e, point{x, y}, palette)
+		return
+	}
+
+	from, to := point{e.from.x, y}, point{e.to.x, y}
+	direction := 1.0
+	if to.x < from.x {
+		direction = -1
+	}
+	from.x += direction * e.offsets[0]
+	to.x -= direction * e.offsets[1]
+	start, stop := from, to
+	var tail, head string
+	if e.head == markerArrow {
+		head, stop = marker(markerArrow, from, to, palette.Stroke, palette.Background)
+	}
+	if e.tail == markerArrow {
+		tail, start = marker(markerArrow, to, from, palette.Stroke, palette.Background)
+	}
+	fmt.Fprintf(b, `<path d="M%.1f,%.1f H%.1f" fill="none" stroke="%s" stroke-width="1.5"%s/>`, start.x, y, stop.x,
+		palette.Stroke, dash)
+	b.WriteByte('\n')
+	b.WriteString(head)
+	b.WriteString(tail)
+	s.writeEnd(b, e.head, from, to, palette)
+	if e.text != "" {
+		writeText(b, e.text, (from.x+to.x)/2, y-4-h/2, palette.Text)
+	}
+	s.writeNumber(b, e, from, palette)
+}
+
+// writeEnd draws the cross of messages that are lost, and the open arrowhead of asynchronous ones.
+func (s *sequence) writeEnd(b *strings.Builder, kind string, from, tip point, palette Palette) {
+	dx := 1.0
+	if tip.x < from.x {
+		dx = -1
+	}
+	switch kind {
+	case "cross":
+		fmt.Fprintf(b, `<path d="M%.1f,%.1f L%.1f,%.1f M%.1f,%.1f L%.1f,%.1f" stroke="%s" stroke-width="2"/>`,
+			tip.x-5, tip.y-5, tip.x+5, tip.y+5, tip.x-5, tip.y+5, tip.x+5, tip.y-5, palette.Stroke)
+		b.WriteByte('\n')
+	case "open":
+		fmt.Fprintf(b, `<path d="M%.1f,%.1f L%.1f,%.1f L%.1f,%.1f" fill="none" stroke="%s" stroke-width="1.5"/>`,
+			tip.x-dx*arrowLength, tip.y-arrowWidth-1, tip.x, tip.y, tip.x-dx*arrowLength, tip.y+arrowWidth+1,
+			palette.Stroke)
+		b.WriteByte('\n')
+	}
+}
+
+// writeNumber draws the number of a message at its start, if messages are numbered.
+func (s *sequence) writeNumber(b *strings.Builder, e *sequenceEvent, at point, palette Palette) {
+	if e.number == 0 {
+		return
+	}
+	fmt.Fprintf(b, `<ellipse cx="%.1f" cy="%.1f" rx="9" ry="9" fill="%s"/>`, at.x, at.y, palette.Stroke)
+	b.WriteByte('\n')
+	writeLabel(b, strconv.Itoa(e.number), at.x, at.y, palette.Background, "middle")
+}
+
+// writeActivations draws the bars on the lifelines of the participants while they are active, nested bars beside
+// each other.
+func (s *sequence) writeActivations(b *strings.Builder, bottom float64, palette Palette) {
+	starts := make(map[*participant][]float64)
+	bar := func(p *participant, depth int, from, to float64) {
+		x := p.x - activationWidth/2 + float64(depth)*activationWidth/2
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%d" height="%.1f" fill="%s" stroke="%s" stroke-width="1"/>`,
+			x, from, activationWidth, math.Max(to-from, 4), palette.Fill, palette.Stroke)
+		b.WriteByte('\n')
+	}
+	for _, e := range s.events {
+		switch e.kind {
+		case eventActivate:
+			starts[e.from] = append(starts[e.from], e.y)
+		case eventDeactivate:
+			if open := starts[e.from]; len(open) > 0 {
+				bar(e.from, len(open)-1, open[len(open)-1], e.y)
+				starts[e.from] = open[:len(open)-1]
+			}
+		}
+	}
+	for p, open := range starts {
+		for depth, from := range open {
+			bar(p, depth, from, bottom)
+		}
+	}
+}
+
+// writeBlock draws a block as a frame with its keyword in the corner, and its sections divided by dashed lines with
+// their conditions.
+func (s *sequence) writeBlock(b *strings.Builder, blk *sequenceBlock, palette Palette) {
+	pad := math.Max(blockPadding-float64(blk.depth)*6, 6)
+	first, last := blk.first, blk.last
+	if first == nil {
+		first, last = s.participants[0], s.participants[len(s.participants)-1]
+	}
+	left, right := first.x-first.w/2-pad+blockPadding/2, math.Max(last.x+last.w/2+pad-blockPadding/2, blk.right)
+	if blk.hidden {
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" opacity="0.5"/>`,
+			left, blk.top, right-left, blk.foot-blk.top, palette.Fill)
+		b.WriteByte('\n')
+		return
+	}
+	fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="none" stroke="%s" stroke-width="1.5"/>`,
+		left, blk.top, right-left, blk.foot-blk.top, palette.Stroke)
+	b.WriteByte('\n')
+	w, _ := labelSize(blk.keyword)
+	w += 2 * padX
+	tab := float64(lineHeight + padY)
+	fmt.Fprintf(b, `<polygon points="%.1f,%.1f %.1f,%.1f %.1f,%.1f %.1f,%.1f %.1f,%.1f" fill="%s" stroke="%s" `+
+		`stroke-width="1.5"/>`, left, blk.top, left+w, blk.top, left+w, blk.top+tab-6, left+w-6, blk.top+tab, left,
+		blk.top+tab, palette.Fill, palette.Stroke)
+	b.WriteByte('\n')
+	writeLabel(b, blk.keyword, left+w/2, blk.top+tab/2, palette.Text, "middle")
+	if blk.labels[0] != "" {
+		writeLabel(b, "["+blk.labels[0]+"]", (left+w+right)/2, blk.top+tab/2, palette.Text, "middle")
+	}
+	for i, y := range blk.dividers {
+		fmt.Fprintf(b, `<path d="M%.1f,%.1f H%.1f" stroke="%s" stroke-width="1.5" stroke-dasharray="5,3"/>`,
+			left, y, right, palette.Stroke)
+		b.WriteByte('\n')
+		if label := blk.labels[i+1]; label != "" {
+			writeLabel(b, "["+label+"]", (left+right)/2, y+tab/2, palette.Text, "middle")
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package diagram
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// State diagram syntax
+var (
+	stateTransition  = regexp.MustCompile(`^(\[\*\]|[\p{L}\p{N}_.]+)\s*-->\s*(\[\*\]|[\p{L}\p{N}_.]+)\s*(?::\s*(.*))?$`)
+	stateAlias       = regexp.MustCompile(`^state\s+"([^"]*)"\s+as\s+([\p{L}\p{N}_.]+)\s*(\{)?$`)
+	stateDeclaration = regexp.MustCompile(`^state\s+([\p{L}\p{N}_.]+)\s*(<<(fork|join|choice)>>)?\s*(\{)?$`)
+	stateDescription = regexp.MustCompile(`^([\p{L}\p{N}_.]+)\s*:\s*(.+)$`)
+	stateNote        = regexp.MustCompile(`^note\s+(?:left|right)\s+of\s+([\p{L}\p{N}_.]+)\s*(?::\s*(.*))?$`)
+	stateClass       = regexp.MustCompile(`:::[\p{L}\p{N}_-]+`)
+)
+
+// Statements that only affect styling or accessibility, which are skipped
+var stateIgnored = []string{"classDef ", "class ", "style ", "hide ", "scale ", "accTitle", "accDescr", "--"}
+
+// stateParser builds a state diagram, in which composite states are flattened into the graph.
+type stateParser struct {
+	g *Graph
+
+	// Composite states around the current statement, innermost last
+	parents []*Node
+	notes   int
+}
+
+// parseState parses a Mermaid state diagram: states with their descriptions, transitions with their labels, start
+// and end states, forks, joins, choices and notes. The states inside a composite state are drawn after it, with a
+// dashed line leading to them.
+func parseState(header string, body []mermaidLine) (*Graph, error) {
+	p := &stateParser{g: newGraph()}
+	for i := 0; i < len(body); i++ {
+		line := body[i]
+		stmt := strings.TrimSpace(stateClass.ReplaceAllString(line.text, ""))
+		if m := stateNote.FindStringSubmatch(stmt); m != nil && m[2] == "" && !strings.Contains(stmt, ":") {
+			// The text of the note follows up to end note
+			var text []string
+			for i++; i < len(body) && strings.TrimSpace(body[i].text) != "end note"; i++ {
+				text = append(text, strings.TrimSpace(body[i].text))
+			}
+			p.note(m[1], strings.Join(text, "\n"))
+			continue
+		}
+		if err := p.statement(line, stmt); err != nil {
+			return nil, err
+		}
+	}
+	return p.g, nil
+}
+
+// statement parses a single statement.
+func (p *stateParser) statement(line mermaidLine, stmt string) error {
+	if strings.HasPrefix(stmt, "direction ") {
+		if len(p.parents) == 0 {
+			direction := strings.TrimSpace(strings.TrimPrefix(stmt, "direction "))
+			p.g.LeftRight = direction == "LR" || direction == "RL"
+		}
+		return nil
+	}
+	for _, prefix := range stateIgnored {
+		if strings.HasPrefix(stmt, prefix) || stmt == strings.TrimSpace(prefix) {
+			return nil
+		}
+	}
+	if stmt == "}" {
+		if len(p.parents) == 0 {
+			return line.errorf("} without a composite state")
+		}
+		p.parents = p.parents[:len(p.parents)-1]
+		return nil
+	}
+	if m := stateTransition.FindStringSubmatch(stmt); m != nil {
+		e := p.g.edge(p.state(m[1], true), p.state(m[2], false))
+		e.Label = mermaidLabel(m[3])
+		e.HeadArrow = true
+		return nil
+	}
+	if m := stateAlias.FindStringSubmatch(stmt); m != nil {
+		n := p.state(m[2], false)
+		n.Label = mermaidLabel(m[1])
+		p.open(n, m[3] != "")
+		return nil
+	}
+	if m := stateDeclaration.FindStringSubmatch(stmt); m != nil {
+		n := p.state(m[1], false)
+		switch m[3] {
+		case "fork", "join":
+			n.Shape, n.Label = shapeBar, ""
+		case "choice":
+			n.Shape, n.Label = shapeDiamond, ""
+		}
+		p.open(n, m[4] != "")
+		return nil
+	}
+	if m := stateNote.FindStringSubmatch(stmt); m != nil {
+		p.note(m[1], m[2])
+		return nil
+	}
+	if m := stateDescription.FindStringSubmatch(stmt); m != nil {
+		n := p.state(m[1], false)
+		n.Label += "\n" + mermaidLabel(m[2])
+		return nil
+	}
+	if m := mermaidID.FindString(stmt); m == stmt {
+		p.state(stmt, false)
+		return nil
+	}
+	return line.errorf("expected a state or a transition at %q", stmt)
+}
+
+// state returns the state with the given ID. [*] stands for the start of the current composite state as the source of
+// a transition, and for its end otherwise.
+func (p *stateParser) state(id string, source bool) *Node {
+	if id != "[*]" {
+		return p.g.node(id, shapeRounded)
+	}
+	scope := ""
+	if len(p.parents) > 0 {
+		scope = p.parents[len(p.parents)-1].ID
+	}
+	if !source {
+		n := p.g.node(scope+"\x00end", shapeEnd)
+		n.Label = ""
+		return n
+	}
+	id = scope + "\x00start"
+	_, exists := p.g.index[id]
+	n := p.g.node(id, shapeStart)
+	n.Label = ""
+	if !exists && scope != "" {
+		link := p.g.edge(p.parents[len(p.parents)-1], n)
+		link.Dashed = true
+	}
+	return n
+}
+
+// open makes n the composite state around the following statements if it has a body.
+func (p *stateParser) open(n *Node, body bool) {
+	if body {
+		p.parents = append(p.parents, n)
+	}
+}
+
+// note attaches a note to the state with the given ID.
+func (p *stateParser) note(id, text string) {
+	p.notes++
+	note := p.g.node(fmt.Sprintf("\x00note%d", p.notes), shapeBox)
+	note.Label = mermaidLabel(text)
+	note.Dashed = true
+	link := p.g.edge(p.state(id, false), note)
+	link.Dashed = true
+}
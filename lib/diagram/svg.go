@@ -0,0 +1,304 @@
+package diagram
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Size of arrowheads, and of the crow's feet of relationships
+const (
+	arrowLength = 10
+	arrowWidth  = 4.5
+	crowLength  = 12
+	crowWidth   = 6
+)
+
+// Palette holds the colours of a drawing. Series are the fills of the slices of a pie chart and other parts told apart
+// by colour, and Accent marks what needs attention, such as critical tasks.
+type Palette struct {
+	Stroke     string
+	Fill       string
+	Text       string
+	Background string
+	Series     []string
+	Accent     string
+}
+
+// Palettes matching the dark and light stylesheets
+var (
+	Dark = Palette{Stroke: "#9ca3af", Fill: "#1f2937", Text: "#e5e7eb", Background: "#111827",
+		Series: []string{"#1e40af", "#991b1b", "#166534", "#92400e", "#5b21b6", "#9d174d", "#155e75", "#9a3412"},
+		Accent: "#f87171"}
+	Light = Palette{Stroke: "#6b7280", Fill: "#f3f4f6", Text: "#1f2937", Background: "#ffffff",
+		Series: []string{"#93c5fd", "#fca5a5", "#86efac", "#fcd34d", "#c4b5fd", "#f9a8d4", "#67e8f9", "#fdba74"},
+		Accent: "#dc2626"}
+)
+
+// Colours that may be copied from a diagram into the SVG: names and hexadecimal RGB(A) values
+var safeColor = regexp.MustCompile(`^(?:[a-zA-Z]{1,32}|#[0-9a-fA-F]{3,8})$`)
+
+// SVG lays out g and draws it as an inline SVG element in the given colours.
+func SVG(g *Graph, palette Palette) string {
+	width, height := layout(g)
+	var b strings.Builder
+	openSVG(&b, width, height)
+	for _, e := range g.Edges {
+		writeEdge(&b, e, palette)
+	}
+	for _, n := range g.Nodes {
+		writeNode(&b, n, palette)
+	}
+	for _, e := range g.Edges {
+		if e.Label != "" {
+			writeEdgeLabel(&b, e, palette)
+		}
+	}
+	b.WriteString("</svg>")
+	return b.String()
+}
+
+// openSVG starts an inline SVG element of the given size.
+func openSVG(b *strings.Builder, width, height float64) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" class="diagram" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" `+
+		`style="max-width:100%%;height:auto" font-family="sans-serif" font-size="%d" role="img">`, width, height, width, height, fontSize)
+	b.WriteByte('\n')
+}
+
+// color returns c if it can be used safely, and otherwise the fallback.
+func color(c, fallback string) string {
+	if safeColor.MatchString(c) {
+		return c
+	}
+	return fallback
+}
+
+// writeNode draws the shape and label of a node.
+func writeNode(b *strings.Builder, n *Node, palette Palette) {
+	stroke := color(n.Stroke, palette.Stroke)
+	fill := color(n.Fill, palette.Fill)
+	style := fmt.Sprintf(`fill="%s" stroke="%s" stroke-width="1.5"`, fill, stroke)
+	if n.Dashed {
+		style += ` stroke-dasharray="5,3"`
+	}
+	x, y, hw, hh := n.x, n.y, n.w/2, n.h/2
+	switch n.Shape {
+	case shapeBox:
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" %s/>`, x-hw, y-hh, n.w, n.h, style)
+	case shapeRounded:
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="6" %s/>`, x-hw, y-hh, n.w, n.h, style)
+	case shapeStadium:
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="%.1f" %s/>`, x-hw, y-hh, n.w, n.h, hh, style)
+	case shapeEllipse, shapeCircle:
+		fmt.Fprintf(b, `<ellipse cx="%.1f" cy="%.1f" rx="%.1f" ry="%.1f" %s/>`, x, y, hw, hh, style)
+	case shapeDiamond:
+		fmt.Fprintf(b, `<polygon points="%.1f,%.1f %.1f,%.1f %.1f,%.1f %.1f,%.1f" %s/>`,
+			x, y-hh, x+hw, y, x, y+hh, x-hw, y, style)
+	case shapeHexagon:
+		inset := n.h / 4
+		fmt.Fprintf(b, `<polygon points="%.1f,%.1f %.1f,%.1f %.1f,%.1f %.1f,%.1f %.1f,%.1f %.1f,%.1f" %s/>`,
+			x-hw, y, x-hw+inset, y-hh, x+hw-inset, y-hh, x+hw, y, x+hw-inset, y+hh, x-hw+inset, y+hh, style)
+	case shapeClass:
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" %s/>`, x-hw, y-hh, n.w, n.h, style)
+		b.WriteByte('\n')
+		writeSections(b, n, stroke, palette.Text)
+		return
+	case shapeStart, shapeBar:
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="%.1f" fill="%s"/>`,
+			x-hw, y-hh, n.w, n.h, math.Min(hw, hh), stroke)
+		b.WriteByte('\n')
+		return
+	case shapeEnd:
+		fmt.Fprintf(b, `<ellipse cx="%.1f" cy="%.1f" rx="%.1f" ry="%.1f" fill="%s" stroke="%s" stroke-width="1.5"/>`,
+			x, y, hw, hh, palette.Background, stroke)
+		fmt.Fprintf(b, `<ellipse cx="%.1f" cy="%.1f" rx="%.1f" ry="%.1f" fill="%s"/>`, x, y, hw-4, hh-4, stroke)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	writeText(b, n.Label, x, y, palette.Text)
+}
+
+// writeSections writes the label of a class in its sections, divided by lines: the name centred, and the members
+// aligned left.
+func writeSections(b *strings.Builder, n *Node, stroke, fill string) {
+	labelLines := lines(n.Label)
+	top := n.y - n.h/2
+	for i, count := range n.Sections {
+		if i > 0 {
+			fmt.Fprintf(b, `<path d="M%.1f,%.1f H%.1f" stroke="%s" stroke-width="1"/>`, n.x-n.w/2, top, n.x+n.w/2, stroke)
+			b.WriteByte('\n')
+		}
+		for j := 0; j < count && len(labelLines) > 0; j++ {
+			y := top + padY/2 + (float64(j)+0.5)*lineHeight
+			if i == 0 {
+				writeLabel(b, labelLines[0], n.x, y, fill, "middle")
+			} else {
+				writeLabel(b, labelLines[0], n.x-n.w/2+padX, y, fill, "start")
+			}
+			labelLines = labelLines[1:]
+		}
+		top += float64(count)*lineHeight + padY
+	}
+}
+
+// writeText draws a possibly multi-line label centred on a point.
+func writeText(b *strings.Builder, label string, x, y float64, fill string) {
+	labelLines := lines(label)
+	top := y - float64(len(labelLines)-1)*lineHeight/2
+	fmt.Fprintf(b, `<text x="%.1f" y="%.1f" fill="%s" text-anchor="middle" dominant-baseline="central">`, x, top, fill)
+	for i, l := range labelLines {
+		if i == 0 {
+			fmt.Fprintf(b, `<tspan x="%.1f">%s</tspan>`, x, html.EscapeString(l))
+		} else {
+			fmt.Fprintf(b, `<tspan x="%.1f" dy="%d">%s</tspan>`, x, lineHeight, html.EscapeString(l))
+		}
+	}
+	b.WriteString("</text>\n")
+}
+
+// writeLabel draws a single line of text at a point, anchored at its start, middle or end.
+func writeLabel(b *strings.Builder, text string, x, y float64, fill, anchor string) {
+	fmt.Fprintf(b, `<text x="%.1f" y="%.1f" fill="%s" text-anchor="%s" dominant-baseline="central">%s</text>`,
+		x, y, fill, anchor, html.EscapeString(text))
+	b.WriteByte('\n')
+}
+
+// writeEdge draws the line and arrowheads of an edge. Lines through dummy nodes are smoothed into curves.
+func writeEdge(b *strings.Builder, e *Edge, palette Palette) {
+	stroke := color(e.Color, palette.Stroke)
+	pts := append([]point(nil), e.points...)
+	if len(pts) < 2 {
+		return
+	}
+	last := len(pts) - 1
+	var head, tail string
+	if e.HeadArrow {
+		head, pts[last] = marker(e.Head, pts[last-1], pts[last], stroke, palette.Background)
+	}
+	if e.TailArrow {
+		tail, pts[0] = marker(e.Tail, pts[1], pts[0], stroke, palette.Background)
+	}
+
+	var d strings.Builder
+	fmt.Fprintf(&d, "M%.1f,%.1f", pts[0].x, pts[0].y)
+	if e.From == e.To {
+		fmt.Fprintf(&d, " C%.1f,%.1f %.1f,%.1f %.1f,%.1f", pts[1].x, pts[1].y, pts[2].x, pts[2].y, pts[3].x, pts[3].y)
+	} else {
+		for i := 1; i < last; i++ {
+			mid := point{(pts[i].x + pts[i+1].x) / 2, (pts[i].y + pts[i+1].y) / 2}
+			fmt.Fprintf(&d, " Q%.1f,%.1f %.1f,%.1f", pts[i].x, pts[i].y, mid.x, mid.y)
+		}
+		fmt.Fprintf(&d, " L%.1f,%.1f", pts[last].x, pts[last].y)
+	}
+
+	width := 1.5
+	if e.Bold {
+		width = 3
+	}
+	dash := ""
+	if e.Dashed {
+		dash = ` stroke-dasharray="5,3"`
+	}
+	fmt.Fprintf(b, `<path d="%s" fill="none" stroke="%s" stroke-width="%.1f"%s/>`, d.String(), stroke, width, dash)
+	b.WriteByte('\n')
+	b.WriteString(head)
+	b.WriteString(tail)
+}
+
+// marker returns the drawing of a marker of the given kind at tip, for a line coming from from, and the point where
+// the line should stop so that it does not poke through the marker.
+func marker(kind string, from, tip point, stroke, background string) (string, point) {
+	dx, dy := tip.x-from.x, tip.y-from.y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return "", tip
+	}
+	ux, uy := dx/length, dy/length
+	// at returns the point the given distances back from the tip along the line and to its side
+	at := func(back, side float64) point {
+		return point{tip.x - ux*back - uy*side, tip.y - uy*back + ux*side}
+	}
+	polygon := func(fill string, pts ...point) string {
+		var s strings.Builder
+		s.WriteString(`<polygon points="`)
+		for i, p := range pts {
+			if i > 0 {
+				s.WriteByte(' ')
+			}
+			fmt.Fprintf(&s, "%.1f,%.1f", p.x, p.y)
+		}
+		fmt.Fprintf(&s, `" fill="%s" stroke="%s" stroke-width="1.5"/>`+"\n", fill, stroke)
+		return s.String()
+	}
+	// Bars across the line, a crow's foot and a circle, for the cardinalities of relationships
+	bar := func(back float64) string {
+		p, q := at(back, crowWidth), at(back, -crowWidth)
+		return fmt.Sprintf(`<path d="M%.1f,%.1f L%.1f,%.1f" stroke="%s" stroke-width="1.5"/>`+"\n", p.x, p.y, q.x, q.y,
+			stroke)
+	}
+	crow := func() string {
+		c, p, q := at(crowLength, 0), at(0, crowWidth), at(0, -crowWidth)
+		return fmt.Sprintf(`<path d="M%.1f,%.1f L%.1f,%.1f M%.1f,%.1f L%.1f,%.1f" fill="none" stroke="%s" `+
+			`stroke-width="1.5"/>`+"\n", p.x, p.y, c.x, c.y, q.x, q.y, c.x, c.y, stroke)
+	}
+	circle := func(back float64) string {
+		c := at(back, 0)
+		return fmt.Sprintf(`<ellipse cx="%.1f" cy="%.1f" rx="4" ry="4" fill="%s" stroke="%s" stroke-width="1.5"/>`+
+			"\n", c.x, c.y, background, stroke)
+	}
+
+	switch kind {
+	case markerTriangle:
+		return polygon(background, tip, at(12, 7), at(12, -7)), at(12, 0)
+	case markerDiamond, markerODiamond:
+		fill := stroke
+		if kind == markerODiamond {
+			fill = background
+		}
+		return polygon(fill, tip, at(8, 5), at(16, 0), at(8, -5)), at(16, 0)
+	case markerOne:
+		return bar(8) + bar(13), tip
+	case markerZeroOne:
+		return bar(8) + circle(17), tip
+	case markerMany:
+		return crow() + bar(crowLength+4), tip
+	case markerZeroMany:
+		return crow() + circle(crowLength+5), tip
+	}
+	return polygon(stroke, tip, at(arrowLength, arrowWidth), at(arrowLength, -arrowWidth)), at(arrowLength, 0)
+}
+
+// writeEdgeLabel draws the label of an edge halfway along it, over a background that hides the line.
+func writeEdgeLabel(b *strings.Builder, e *Edge, palette Palette) {
+	mid := halfway(e.points)
+	if e.From == e.To {
+		mid = point{e.points[1].x, e.From.y}
+	}
+	w, h := labelSize(e.Label)
+	w += 4
+	fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" opacity="0.85"/>`,
+		mid.x-w/2, mid.y-h/2, w, h, palette.Background)
+	b.WriteByte('\n')
+	writeText(b, e.Label, mid.x, mid.y, palette.Text)
+}
+
+// halfway returns the point halfway along a polyline.
+func halfway(pts []point) point {
+	total := 0.0
+	for i := 1; i < len(pts); i++ {
+		total += math.Hypot(pts[i].x-pts[i-1].x, pts[i].y-pts[i-1].y)
+	}
+	remaining := total / 2
+	for i := 1; i < len(pts); i++ {
+		seg := math.Hypot(pts[i].x-pts[i-1].x, pts[i].y-pts[i-1].y)
+		if seg >= remaining && seg > 0 {
+			t := remaining / seg
+			return point{pts[i-1].x + t*(pts[i].x-pts[i-1].x), pts[i-1].y + t*(pts[i].y-pts[i-1].y)}
+		}
+		remaining -= seg
+	}
+	return pts[0]
+}
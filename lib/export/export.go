@@ -0,0 +1,134 @@
+package export
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/dienakakim/mds/lib/render"
+	. "github.com/dienakakim/mds/lib/structs"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+)
+
+// Export renders every Markdown file below src into an HTML file below out, using the same Goldmark instance and
+// template as the server. Links to Markdown files are rewritten to point to their HTML counterparts, and all other
+// files are copied verbatim.
+func Export(src, out string, gm goldmark.Markdown, templ *template.Template, config Config) error {
+	absOut, err := filepath.Abs(out)
+	if err != nil {
+		return err
+	}
+	config.LiveReload = false
+
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if abs, _ := filepath.Abs(p); abs == absOut {
+			// Never export the output into itself
+			return filepath.SkipDir
+		}
+		if p != src && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(out, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !strings.HasSuffix(p, ".md") {
+			log.Printf("Copying %s", rel)
+			return copyFile(p, target, info.Mode())
+		}
+
+		log.Printf("Rendering %s", rel)
+		html := htmlName(target)
+		if err := exportFile(p, html, gm, templ, config); err != nil {
+			return err
+		}
+		// Directories show their README when they have no index
+		if info.Name() == "README.md" {
+			if _, err := os.Stat(filepath.Join(filepath.Dir(p), "index.md")); os.IsNotExist(err) {
+				return copyFile(html, filepath.Join(filepath.Dir(html), "index.html"), 0644)
+			}
+		}
+		return nil
+	})
+}
+
+// exportFile renders the Markdown file src into the HTML file dst.
+func exportFile(src, dst string, gm goldmark.Markdown, templ *template.Template, config Config) error {
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	config.FileName = src
+	rendered := Build(gm, content, config, rewriteLinks)
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err := templ.Execute(f, rendered); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %v", src, err)
+	}
+	return f.Close()
+}
+
+// rewriteLinks points every local link to a Markdown file at the exported HTML file instead.
+func rewriteLinks(doc ast.Node) {
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if link, ok := n.(*ast.Link); ok && entering {
+			link.Destination = []byte(rewriteDestination(string(link.Destination)))
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// rewriteDestination changes the extension of a local Markdown link destination to .html, keeping any query or
+// fragment. Other destinations are returned unchanged.
+func rewriteDestination(dest string) string {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasSuffix(u.Path, ".md") {
+		return dest
+	}
+	u.Path = htmlName(u.Path)
+	return u.String()
+}
+
+// htmlName replaces the .md extension of name with .html.
+func htmlName(name string) string {
+	return strings.TrimSuffix(name, ".md") + ".html"
+}
+
+// copyFile copies the file src to dst verbatim.
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,217 @@
+package mathml
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindInlineMath is the NodeKind of math inside a paragraph.
+var KindInlineMath = ast.NewNodeKind("InlineMath")
+
+// KindMathBlock is the NodeKind of math standing on its own between $$ lines.
+var KindMathBlock = ast.NewNodeKind("MathBlock")
+
+// InlineMath is math written between $...$, $$...$$, \\(...\\) or \\[...\\].
+type InlineMath struct {
+	ast.BaseInline
+	TeX     []byte
+	Display bool
+}
+
+// Kind implements ast.Node.
+func (n *InlineMath) Kind() ast.NodeKind {
+	return KindInlineMath
+}
+
+// Dump implements ast.Node.
+func (n *InlineMath) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"TeX": string(n.TeX)}, nil)
+}
+
+// MathBlock is display math written between lines consisting of $$.
+type MathBlock struct {
+	ast.BaseBlock
+}
+
+// Kind implements ast.Node.
+func (n *MathBlock) Kind() ast.NodeKind {
+	return KindMathBlock
+}
+
+// IsRaw implements ast.Node.
+func (n *MathBlock) IsRaw() bool {
+	return true
+}
+
+// Dump implements ast.Node.
+func (n *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// Opening and closing delimiters of inline math, longest first
+var inlineDelimiters = []struct {
+	open, close string
+	display     bool
+}{
+	{`\\(`, `\\)`, false},
+	{`\\[`, `\\]`, true},
+	{"$$", "$$", true},
+	{"$", "$", false},
+}
+
+// inlineMathParser parses math inside paragraphs.
+type inlineMathParser struct{}
+
+// Trigger implements parser.InlineParser.
+func (p *inlineMathParser) Trigger() []byte {
+	return []byte{'$', '\\'}
+}
+
+// Parse implements parser.InlineParser.
+func (p *inlineMathParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	for _, d := range inlineDelimiters {
+		if !bytes.HasPrefix(line, []byte(d.open)) {
+			continue
+		}
+		if d.open == "$" && (len(line) < 2 || util.IsSpace(line[1])) {
+			// "$ " is not an opening dollar sign
+			return nil
+		}
+		l, pos := block.Position()
+		block.Advance(len(d.open))
+		var tex []byte
+		for {
+			line, _ := block.PeekLine()
+			if line == nil {
+				block.SetPosition(l, pos)
+				return nil
+			}
+			if i := closing(line, d.open, d.close); i >= 0 {
+				tex = append(tex, line[:i]...)
+				block.Advance(i + len(d.close))
+				return &InlineMath{TeX: tex, Display: d.display}
+			}
+			tex = append(tex, line...)
+			block.AdvanceLine()
+		}
+	}
+	return nil
+}
+
+// closing returns the position of the closing delimiter within line, or -1 if it does not close there.
+func closing(line []byte, open, close string) int {
+	for i := 0; i+len(close) <= len(line); i++ {
+		if line[i] == '\\' && (open == "$" || open == "$$") {
+			// Escaped dollar sign
+			i++
+			continue
+		}
+		if !bytes.HasPrefix(line[i:], []byte(close)) {
+			continue
+		}
+		if open == "$" {
+			// Closing dollar signs follow a non-space and are not followed by a digit
+			if i == 0 || util.IsSpace(line[i-1]) || i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9' {
+				continue
+			}
+		}
+		return i
+	}
+	return -1
+}
+
+// mathBlockParser parses display math between lines consisting of $$.
+type mathBlockParser struct{}
+
+// Trigger implements parser.BlockParser.
+func (p *mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Open implements parser.BlockParser.
+func (p *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, _ := reader.PeekLine()
+	if !bytes.Equal(util.TrimRightSpace(util.TrimLeftSpace(line)), []byte("$$")) {
+		// Math on a single line is left to the inline parser
+		return nil, parser.NoChildren
+	}
+	return &MathBlock{}, parser.NoChildren
+}
+
+// Continue implements parser.BlockParser.
+func (p *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	if bytes.Equal(util.TrimRightSpace(util.TrimLeftSpace(line)), []byte("$$")) {
+		reader.Advance(segment.Len())
+		return parser.Close
+	}
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	return parser.Continue | parser.NoChildren
+}
+
+// Close implements parser.BlockParser.
+func (p *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+// CanInterruptParagraph implements parser.BlockParser.
+func (p *mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+// CanAcceptIndentedLine implements parser.BlockParser.
+func (p *mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// mathRenderer renders math nodes as MathML.
+type mathRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (r *mathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindInlineMath, r.renderInlineMath)
+	reg.Register(KindMathBlock, r.renderMathBlock)
+}
+
+func (r *mathRenderer) renderInlineMath(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		node := n.(*InlineMath)
+		w.WriteString(ToMathML(string(node.TeX), node.Display))
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *mathRenderer) renderMathBlock(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		var tex bytes.Buffer
+		lines := n.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			segment := lines.At(i)
+			tex.Write(segment.Value(source))
+		}
+		w.WriteString(ToMathML(tex.String(), true))
+		w.WriteByte('\n')
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// mathExtension renders TeX math on the server as MathML, which browsers display without any script.
+type mathExtension struct{}
+
+// Math is the goldmark extension converting TeX math into MathML.
+var Math = &mathExtension{}
+
+// Extend implements goldmark.Extender.
+func (e *mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&mathBlockParser{}, 650)),
+		parser.WithInlineParsers(util.Prioritized(&inlineMathParser{}, 150)),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&mathRenderer{}, 500)))
+}
@@ -0,0 +1,124 @@
+package mathml
+
+// Greek letters and other symbols rendered as identifiers
+var identifiers = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ", "varepsilon": "ε", "zeta": "ζ",
+	"eta": "η", "theta": "θ", "vartheta": "ϑ", "iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν",
+	"xi": "ξ", "pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ", "sigma": "σ", "varsigma": "ς", "tau": "τ",
+	"upsilon": "υ", "phi": "ϕ", "varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"infty": "∞", "partial": "∂", "nabla": "∇", "hbar": "ℏ", "ell": "ℓ", "emptyset": "∅", "varnothing": "∅",
+	"aleph": "ℵ", "Re": "ℜ", "Im": "ℑ", "wp": "℘", "angle": "∠", "triangle": "△", "top": "⊤", "bot": "⊥",
+	"imath": "ı", "jmath": "ȷ",
+}
+
+// Upright identifiers, such as capital Greek letters
+var uprightIdentifiers = map[string]string{
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π", "Sigma": "Σ",
+	"Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+}
+
+// Binary operators, relations, arrows and punctuation
+var operators = map[string]string{
+	"pm": "±", "mp": "∓", "times": "×", "div": "÷", "cdot": "⋅", "ast": "∗", "star": "⋆", "circ": "∘",
+	"bullet": "∙", "oplus": "⊕", "ominus": "⊖", "otimes": "⊗", "oslash": "⊘", "odot": "⊙", "cup": "∪",
+	"cap": "∩", "setminus": "∖", "wedge": "∧", "land": "∧", "vee": "∨", "lor": "∨", "neg": "¬", "lnot": "¬",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠", "ll": "≪", "gg": "≫", "approx": "≈",
+	"sim": "∼", "simeq": "≃", "cong": "≅", "equiv": "≡", "propto": "∝", "prec": "≺", "succ": "≻",
+	"preceq": "⪯", "succeq": "⪰", "subset": "⊂", "supset": "⊃", "subseteq": "⊆", "supseteq": "⊇", "in": "∈",
+	"notin": "∉", "ni": "∋", "mid": "∣", "parallel": "∥", "perp": "⊥", "models": "⊨", "vdash": "⊢",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←", "leftrightarrow": "↔", "Rightarrow": "⇒",
+	"Leftarrow": "⇐", "Leftrightarrow": "⇔", "implies": "⟹", "iff": "⟺", "mapsto": "↦",
+	"longrightarrow": "⟶", "longleftarrow": "⟵", "uparrow": "↑", "downarrow": "↓", "forall": "∀",
+	"exists": "∃", "nexists": "∄", "ldots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱", "dots": "…",
+	"colon": ":", "langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋", "lceil": "⌈", "rceil": "⌉",
+	"vert": "|", "Vert": "‖", "lbrace": "{", "rbrace": "}", "backslash": "\\", "therefore": "∴",
+	"because": "∵", "dagger": "†", "wr": "≀",
+}
+
+// Large operators taking limits above and below in display mode
+var largeOperators = map[string]string{
+	"sum": "∑", "prod": "∏", "coprod": "∐", "bigcup": "⋃", "bigcap": "⋂", "bigoplus": "⨁",
+	"bigotimes": "⨂", "bigvee": "⋁", "bigwedge": "⋀",
+}
+
+// Integrals, which always take their limits as scripts
+var integrals = map[string]string{
+	"int": "∫", "iint": "∬", "iiint": "∭", "oint": "∮",
+}
+
+// Named functions set in upright type
+var functions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true, "csc": true, "arcsin": true,
+	"arccos": true, "arctan": true, "sinh": true, "cosh": true, "tanh": true, "coth": true, "log": true,
+	"ln": true, "lg": true, "exp": true, "det": true, "dim": true, "ker": true, "deg": true, "gcd": true,
+	"arg": true, "hom": true, "Pr": true,
+}
+
+// Named functions taking limits above and below in display mode
+var limitFunctions = map[string]string{
+	"lim": "lim", "limsup": "lim sup", "liminf": "lim inf", "max": "max", "min": "min", "sup": "sup",
+	"inf": "inf",
+}
+
+// Accents placed over their argument
+var accents = map[string]string{
+	"hat": "^", "widehat": "^", "bar": "¯", "overline": "‾", "vec": "→", "overrightarrow": "→",
+	"dot": "˙", "ddot": "¨", "tilde": "˜", "widetilde": "˜", "check": "ˇ", "breve": "˘", "acute": "´",
+	"grave": "`",
+}
+
+// Horizontal spacing commands and their widths
+var spaces = map[string]string{
+	",": "0.1667em", ":": "0.2222em", ">": "0.2222em", ";": "0.2778em", "!": "-0.1667em", " ": "0.25em",
+	"quad": "1em", "qquad": "2em",
+}
+
+// Commands escaping a single character
+var escapedChars = map[string]string{
+	"{": "{", "}": "}", "%": "%", "$": "$", "#": "#", "&": "&", "_": "_", "|": "‖",
+}
+
+// Font commands and the Unicode mathematical alphabet they select
+var fonts = map[string]string{
+	"mathbf": "bold", "mathit": "italic", "mathcal": "script", "mathscr": "script", "mathfrak": "fraktur",
+	"mathbb": "double-struck", "mathsf": "sans-serif", "mathtt": "monospace", "mathrm": "normal",
+	"boldsymbol": "bold",
+}
+
+// alphabet locates one Unicode mathematical alphabet.
+type alphabet struct {
+	upper, lower, digit rune
+	holes               map[rune]rune
+}
+
+// Unicode mathematical alphabets, with the letters that live in the Letterlike Symbols block
+var alphabets = map[string]alphabet{
+	"bold":          {upper: 0x1D400, lower: 0x1D41A, digit: 0x1D7CE},
+	"italic":        {upper: 0x1D434, lower: 0x1D44E, holes: map[rune]rune{'h': 'ℎ'}},
+	"script":        {upper: 0x1D49C, lower: 0x1D4B6, holes: map[rune]rune{'B': 'ℬ', 'E': 'ℰ', 'F': 'ℱ', 'H': 'ℋ', 'I': 'ℐ', 'L': 'ℒ', 'M': 'ℳ', 'R': 'ℛ', 'e': 'ℯ', 'g': 'ℊ', 'o': 'ℴ'}},
+	"fraktur":       {upper: 0x1D504, lower: 0x1D51E, holes: map[rune]rune{'C': 'ℭ', 'H': 'ℌ', 'I': 'ℑ', 'R': 'ℜ', 'Z': 'ℨ'}},
+	"double-struck": {upper: 0x1D538, lower: 0x1D552, digit: 0x1D7D8, holes: map[rune]rune{'C': 'ℂ', 'H': 'ℍ', 'N': 'ℕ', 'P': 'ℙ', 'Q': 'ℚ', 'R': 'ℝ', 'Z': 'ℤ'}},
+	"sans-serif":    {upper: 0x1D5A0, lower: 0x1D5BA, digit: 0x1D7E2},
+	"monospace":     {upper: 0x1D670, lower: 0x1D68A, digit: 0x1D7F6},
+}
+
+// styled maps an ASCII letter or digit to the given mathematical alphabet, returning it unchanged if the alphabet
+// has no such character.
+func styled(variant string, r rune) rune {
+	a, ok := alphabets[variant]
+	if !ok {
+		return r
+	}
+	if h, ok := a.holes[r]; ok {
+		return h
+	}
+	switch {
+	case r >= 'A' && r <= 'Z':
+		return a.upper + r - 'A'
+	case r >= 'a' && r <= 'z':
+		return a.lower + r - 'a'
+	case r >= '0' && r <= '9' && a.digit != 0:
+		return a.digit + r - '0'
+	}
+	return r
+}
@@ -0,0 +1,489 @@
+package mathml
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Kinds of TeX tokens
+const (
+	tokEOF = iota
+	tokChar
+	tokCommand
+)
+
+// token is a single character or control sequence of TeX input.
+type token struct {
+	kind int
+	text string
+}
+
+// isChar reports whether t is the character s.
+func (t token) isChar(s string) bool {
+	return t.kind == tokChar && t.text == s
+}
+
+// isCommand reports whether t is the control sequence \name.
+func (t token) isCommand(name string) bool {
+	return t.kind == tokCommand && t.text == name
+}
+
+// atom is the MathML produced for a single TeX atom, before scripts are attached.
+type atom struct {
+	markup string
+	limits bool // scripts go above and below instead of to the right
+	apply  bool // a function name, followed by an invisible function application
+}
+
+// converter turns a TeX math expression into presentation MathML.
+type converter struct {
+	src     []rune
+	pos     int
+	variant string
+}
+
+// ToMathML converts a TeX math expression to presentation MathML. The original TeX source is kept as an annotation,
+// so it is still available for copying. Unsupported commands are rendered as errors instead of failing the whole
+// expression.
+func ToMathML(tex string, display bool) string {
+	c := &converter{src: []rune(tex)}
+	items := c.parseSeq(func(token) bool { return false })
+	mode := "inline"
+	if display {
+		mode = "block"
+	}
+	return `<math xmlns="http://www.w3.org/1998/Math/MathML" display="` + mode + `"><semantics>` + row(items) +
+		`<annotation encoding="application/x-tex">` + escape(tex) + `</annotation></semantics></math>`
+}
+
+// peek returns the next token without consuming it.
+func (c *converter) peek() token {
+	pos := c.pos
+	t := c.next()
+	c.pos = pos
+	return t
+}
+
+// next consumes and returns the next token, skipping whitespace as TeX does in math mode.
+func (c *converter) next() token {
+	for c.pos < len(c.src) && unicode.IsSpace(c.src[c.pos]) {
+		c.pos++
+	}
+	if c.pos >= len(c.src) {
+		return token{kind: tokEOF}
+	}
+	r := c.src[c.pos]
+	c.pos++
+	if r != '\\' {
+		return token{kind: tokChar, text: string(r)}
+	}
+	if c.pos >= len(c.src) {
+		return token{kind: tokCommand}
+	}
+	start := c.pos
+	for c.pos < len(c.src) && isASCIILetter(c.src[c.pos]) {
+		c.pos++
+	}
+	if c.pos == start {
+		// Control symbol, such as \, or \{
+		c.pos++
+	}
+	return token{kind: tokCommand, text: string(c.src[start:c.pos])}
+}
+
+// rawGroup consumes a braced group and returns its contents verbatim. A single token is returned if there is no
+// group.
+func (c *converter) rawGroup() string {
+	t := c.next()
+	if !t.isChar("{") {
+		if t.kind == tokCommand {
+			return "\\" + t.text
+		}
+		return t.text
+	}
+	start, depth := c.pos, 1
+	for ; c.pos < len(c.src); c.pos++ {
+		switch c.src[c.pos] {
+		case '\\':
+			c.pos++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				text := string(c.src[start:c.pos])
+				c.pos++
+				return text
+			}
+		}
+	}
+	return string(c.src[start:])
+}
+
+// parseSeq parses atoms until the input ends or stop matches the next token, which is left unconsumed.
+func (c *converter) parseSeq(stop func(token) bool) []string {
+	var items []string
+	for {
+		t := c.peek()
+		if t.kind == tokEOF || stop(t) {
+			return items
+		}
+		if markup := c.parseAtom(); markup != "" {
+			items = append(items, markup)
+		}
+	}
+}
+
+// parseAtom parses a single atom along with its subscripts, superscripts and primes.
+func (c *converter) parseAtom() string {
+	var base atom
+	if t := c.peek(); !t.isChar("^") && !t.isChar("_") && !t.isChar("'") {
+		base = c.parsePrimary(false)
+		if base.markup == "" {
+			return ""
+		}
+	} else {
+		base.markup = "<mrow></mrow>"
+	}
+
+	var sub, sup []string
+	for {
+		t := c.peek()
+		switch {
+		case t.isChar("^"):
+			c.next()
+			sup = append(sup, c.parseArg())
+		case t.isChar("_"):
+			c.next()
+			sub = append(sub, c.parseArg())
+		case t.isChar("'"):
+			c.next()
+			sup = append(sup, "<mo>′</mo>")
+		case t.isCommand("limits"):
+			c.next()
+			base.limits = true
+		case t.isCommand("nolimits"):
+			c.next()
+			base.limits = false
+		default:
+			return c.attach(base, sub, sup)
+		}
+	}
+}
+
+// attach places scripts on a base atom.
+func (c *converter) attach(base atom, sub, sup []string) string {
+	var markup string
+	switch {
+	case sub == nil && sup == nil:
+		markup = base.markup
+	case sup == nil && base.limits:
+		markup = "<munder>" + base.markup + row(sub) + "</munder>"
+	case sup == nil:
+		markup = "<msub>" + base.markup + row(sub) + "</msub>"
+	case sub == nil && base.limits:
+		markup = "<mover>" + base.markup + row(sup) + "</mover>"
+	case sub == nil:
+		markup = "<msup>" + base.markup + row(sup) + "</msup>"
+	case base.limits:
+		markup = "<munderover>" + base.markup + row(sub) + row(sup) + "</munderover>"
+	default:
+		markup = "<msubsup>" + base.markup + row(sub) + row(sup) + "</msubsup>"
+	}
+	if base.apply {
+		markup += "<mo>&#x2061;</mo>"
+	}
+	return markup
+}
+
+// parseArg parses the argument of a command or script: either a braced group or a single token.
+func (c *converter) parseArg() string {
+	if c.peek().isChar("{") {
+		c.next()
+		items := c.parseSeq(func(t token) bool { return t.isChar("}") })
+		c.next()
+		return row(items)
+	}
+	return c.parsePrimary(true).markup
+}
+
+// parsePrimary parses an atom without scripts. When single is set, numbers are limited to one digit, as TeX does
+// for unbraced arguments.
+func (c *converter) parsePrimary(single bool) atom {
+	t := c.next()
+	switch t.kind {
+	case tokEOF:
+		return atom{}
+	case tokChar:
+		return c.parseChar(t.text, single)
+	}
+
+	name := t.text
+	switch name {
+	case "frac", "dfrac", "tfrac", "cfrac":
+		num := c.parseArg()
+		return atom{markup: "<mfrac>" + num + c.parseArg() + "</mfrac>"}
+	case "binom", "dbinom", "tbinom":
+		top := c.parseArg()
+		return atom{markup: `<mrow><mo>(</mo><mfrac linethickness="0">` + top + c.parseArg() + `</mfrac><mo>)</mo></mrow>`}
+	case "sqrt":
+		if c.peek().isChar("[") {
+			c.next()
+			index := c.parseSeq(func(t token) bool { return t.isChar("]") })
+			c.next()
+			return atom{markup: "<mroot>" + c.parseArg() + row(index) + "</mroot>"}
+		}
+		return atom{markup: "<msqrt>" + c.parseArg() + "</msqrt>"}
+	case "left":
+		open := c.delimiter()
+		items := c.parseSeq(func(t token) bool { return t.isCommand("right") })
+		c.next()
+		return atom{markup: "<mrow>" + fence(open) + strings.Join(items, "") + fence(c.delimiter()) + "</mrow>"}
+	case "middle":
+		return atom{markup: fence(c.delimiter())}
+	case "big", "bigl", "bigr", "bigm":
+		return atom{markup: sized(c.delimiter(), "1.2em")}
+	case "Big", "Bigl", "Bigr", "Bigm":
+		return atom{markup: sized(c.delimiter(), "1.623em")}
+	case "bigg", "biggl", "biggr", "biggm":
+		return atom{markup: sized(c.delimiter(), "2.047em")}
+	case "Bigg", "Biggl", "Biggr", "Biggm":
+		return atom{markup: sized(c.delimiter(), "2.470em")}
+	case "text", "textrm", "textnormal", "mbox", "hbox":
+		return atom{markup: "<mtext>" + escape(c.rawGroup()) + "</mtext>"}
+	case "textbf":
+		return atom{markup: `<mtext mathvariant="bold">` + escape(c.rawGroup()) + "</mtext>"}
+	case "textit":
+		return atom{markup: `<mtext mathvariant="italic">` + escape(c.rawGroup()) + "</mtext>"}
+	case "operatorname":
+		return atom{markup: "<mi>" + escape(c.rawGroup()) + "</mi>", apply: true}
+	case "underline":
+		return atom{markup: `<munder accentunder="true">` + c.parseArg() + `<mo stretchy="true">_</mo></munder>`}
+	case "overbrace":
+		return atom{markup: "<mover>" + c.parseArg() + `<mo stretchy="true">⏞</mo></mover>`, limits: true}
+	case "underbrace":
+		return atom{markup: "<munder>" + c.parseArg() + `<mo stretchy="true">⏟</mo></munder>`, limits: true}
+	case "overset", "stackrel":
+		over := c.parseArg()
+		return atom{markup: "<mover>" + c.parseArg() + over + "</mover>"}
+	case "underset":
+		under := c.parseArg()
+		return atom{markup: "<munder>" + c.parseArg() + under + "</munder>"}
+	case "begin":
+		return atom{markup: c.environment(c.rawGroup())}
+	case "end":
+		c.rawGroup()
+		return atom{}
+	case "right", "\\", "displaystyle", "textstyle", "scriptstyle", "limits", "nolimits":
+		// Stray or purely presentational commands
+		return atom{}
+	}
+
+	if v, ok := fonts[name]; ok {
+		saved := c.variant
+		c.variant = v
+		arg := c.parseArg()
+		c.variant = saved
+		return atom{markup: arg}
+	}
+	if a, ok := accents[name]; ok {
+		stretchy := "false"
+		if strings.HasPrefix(name, "wide") || strings.HasPrefix(name, "over") {
+			stretchy = "true"
+		}
+		return atom{markup: `<mover accent="true">` + c.parseArg() + `<mo stretchy="` + stretchy + `">` + escape(a) + "</mo></mover>"}
+	}
+	if w, ok := spaces[name]; ok {
+		return atom{markup: `<mspace width="` + w + `"/>`}
+	}
+	if s, ok := escapedChars[name]; ok {
+		return atom{markup: "<mo>" + escape(s) + "</mo>"}
+	}
+	if s, ok := identifiers[name]; ok {
+		return atom{markup: "<mi>" + s + "</mi>"}
+	}
+	if s, ok := uprightIdentifiers[name]; ok {
+		return atom{markup: `<mi mathvariant="normal">` + s + "</mi>"}
+	}
+	if s, ok := operators[name]; ok {
+		return atom{markup: "<mo>" + escape(s) + "</mo>"}
+	}
+	if s, ok := largeOperators[name]; ok {
+		return atom{markup: `<mo largeop="true" movablelimits="true">` + s + "</mo>", limits: true}
+	}
+	if s, ok := integrals[name]; ok {
+		return atom{markup: `<mo largeop="true">` + s + "</mo>"}
+	}
+	if functions[name] {
+		return atom{markup: "<mi>" + name + "</mi>", apply: true}
+	}
+	if s, ok := limitFunctions[name]; ok {
+		return atom{markup: `<mo movablelimits="true">` + s + "</mo>", limits: true}
+	}
+	return atom{markup: "<merror><mtext>\\" + escape(name) + "</mtext></merror>"}
+}
+
+// parseChar converts a single input character, or a number starting with it.
+func (c *converter) parseChar(s string, single bool) atom {
+	switch s {
+	case "{":
+		items := c.parseSeq(func(t token) bool { return t.isChar("}") })
+		c.next()
+		return atom{markup: row(items)}
+	case "}", "&":
+		// Stray group end or column separator
+		return atom{}
+	case "~":
+		return atom{markup: `<mspace width="0.25em"/>`}
+	}
+
+	r := []rune(s)[0]
+	switch {
+	case unicode.IsDigit(r):
+		number := s
+		for !single && c.pos < len(c.src) {
+			next := c.src[c.pos]
+			if unicode.IsDigit(next) || next == '.' && c.pos+1 < len(c.src) && unicode.IsDigit(c.src[c.pos+1]) {
+				number += string(next)
+				c.pos++
+			} else {
+				break
+			}
+		}
+		return atom{markup: "<mn>" + c.style(number) + "</mn>"}
+	case unicode.IsLetter(r):
+		if c.variant == "normal" {
+			return atom{markup: `<mi mathvariant="normal">` + s + "</mi>"}
+		}
+		return atom{markup: "<mi>" + c.style(s) + "</mi>"}
+	case s == "-":
+		return atom{markup: "<mo>−</mo>"}
+	case s == "*":
+		return atom{markup: "<mo>∗</mo>"}
+	}
+	return atom{markup: "<mo>" + escape(s) + "</mo>"}
+}
+
+// style maps the letters and digits of s to the current font.
+func (c *converter) style(s string) string {
+	if c.variant == "" || c.variant == "normal" {
+		return s
+	}
+	return strings.Map(func(r rune) rune { return styled(c.variant, r) }, s)
+}
+
+// delimiter consumes the delimiter following \left, \right or \big and friends. A period means no delimiter.
+func (c *converter) delimiter() string {
+	t := c.next()
+	switch t.kind {
+	case tokChar:
+		if t.text == "." {
+			return ""
+		}
+		return t.text
+	case tokCommand:
+		if s, ok := escapedChars[t.text]; ok {
+			return s
+		}
+		if s, ok := operators[t.text]; ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// environment converts the body of \begin{name} ... \end{name} into a table.
+func (c *converter) environment(name string) string {
+	if name == "array" {
+		// Column specification
+		c.rawGroup()
+	}
+
+	var rows []string
+	var cells []string
+	for {
+		items := c.parseSeq(func(t token) bool {
+			return t.isChar("&") || t.isCommand("\\") || t.isCommand("end")
+		})
+		cells = append(cells, "<mtd>"+strings.Join(items, "")+"</mtd>")
+		t := c.next()
+		if t.isChar("&") {
+			continue
+		}
+		if t.isCommand("\\") && c.peek().isChar("[") {
+			// Extra row spacing
+			for t := c.next(); t.kind != tokEOF && !t.isChar("]"); t = c.next() {
+			}
+		}
+		// A trailing \\ does not start a new row
+		if !(len(cells) == 1 && cells[0] == "<mtd></mtd>" && !t.isCommand("\\")) {
+			rows = append(rows, "<mtr>"+strings.Join(cells, "")+"</mtr>")
+		}
+		cells = nil
+		if !t.isCommand("\\") {
+			if t.isCommand("end") {
+				c.rawGroup()
+			}
+			break
+		}
+	}
+
+	attrs := ""
+	switch name {
+	case "cases":
+		attrs = ` columnalign="left left"`
+	case "aligned", "align", "align*", "split", "eqnarray", "eqnarray*", "alignat", "alignat*":
+		attrs = ` columnalign="right left" columnspacing="0em"`
+	}
+	table := "<mtable" + attrs + ">" + strings.Join(rows, "") + "</mtable>"
+
+	switch name {
+	case "pmatrix":
+		return "<mrow>" + fence("(") + table + fence(")") + "</mrow>"
+	case "bmatrix":
+		return "<mrow>" + fence("[") + table + fence("]") + "</mrow>"
+	case "Bmatrix":
+		return "<mrow>" + fence("{") + table + fence("}") + "</mrow>"
+	case "vmatrix":
+		return "<mrow>" + fence("|") + table + fence("|") + "</mrow>"
+	case "Vmatrix":
+		return "<mrow>" + fence("‖") + table + fence("‖") + "</mrow>"
+	case "cases":
+		return "<mrow>" + fence("{") + table + "</mrow>"
+	}
+	return table
+}
+
+// fence returns a stretchy delimiter, or nothing for an empty one.
+func fence(d string) string {
+	if d == "" {
+		return ""
+	}
+	return `<mo fence="true" stretchy="true">` + escape(d) + "</mo>"
+}
+
+// sized returns a delimiter of a fixed size.
+func sized(d, size string) string {
+	if d == "" {
+		return ""
+	}
+	return `<mo stretchy="true" minsize="` + size + `" maxsize="` + size + `">` + escape(d) + "</mo>"
+}
+
+// row groups several elements so they can act as a single argument.
+func row(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return "<mrow>" + strings.Join(items, "") + "</mrow>"
+}
+
+// escape escapes text for inclusion in MathML.
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(s)
+}
+
+// isASCIILetter reports whether r can be part of a control word.
+func isASCIILetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
@@ -0,0 +1,210 @@
+package nav
+
+import (
+	"io/ioutil"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	. "github.com/dienakakim/mds/lib/structs"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Manifests listing the pages of a folder in reading order, as used by mdBook and docsify
+var manifests = []string{"SUMMARY.md", "_sidebar.md"}
+
+// Files standing for the folder they are in, as they are served for it
+var indexFiles = []string{"README.md", "index.md"}
+
+// Tree returns the navigation tree of pages, the documents below dir. The first manifest found in dir gives the
+// entries and their order; otherwise the tree follows the folders, with each folder leading to its index file.
+func Tree(dir string, gm goldmark.Markdown, pages []PageLink) []NavItem {
+	titles := make(map[string]string, len(pages))
+	for _, p := range pages {
+		titles[p.Path] = p.Title
+	}
+	for _, name := range manifests {
+		if source, err := ioutil.ReadFile(filepath.Join(dir, name)); err == nil {
+			doc := gm.Parser().Parse(text.NewReader(source))
+			return fromManifest(doc, source, titles)
+		}
+	}
+	return fromFolders(pages)
+}
+
+// fromManifest reads the entries of a manifest: its links, nested as the lists holding them, and the headings
+// separating parts of a book, except the first.
+func fromManifest(doc ast.Node, source []byte, titles map[string]string) []NavItem {
+	var items []NavItem
+	headings := 0
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		switch node := n.(type) {
+		case *ast.Heading:
+			if headings++; headings > 1 {
+				items = append(items, NavItem{Title: string(node.Text(source))})
+			}
+		case *ast.List:
+			items = append(items, listItems(node, source, titles)...)
+		case *ast.Paragraph:
+			for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+				if link, ok := c.(*ast.Link); ok {
+					items = append(items, linkItem(link, source, titles))
+				}
+			}
+		}
+	}
+	return items
+}
+
+// listItems reads the entries of a list in a manifest.
+func listItems(list *ast.List, source []byte, titles map[string]string) []NavItem {
+	var items []NavItem
+	for li := list.FirstChild(); li != nil; li = li.NextSibling() {
+		var item NavItem
+		for c := li.FirstChild(); c != nil; c = c.NextSibling() {
+			if sublist, ok := c.(*ast.List); ok {
+				item.Children = append(item.Children, listItems(sublist, source, titles)...)
+				continue
+			}
+			if item.Title != "" {
+				continue
+			}
+			if link, ok := c.FirstChild().(*ast.Link); ok {
+				linked := linkItem(link, source, titles)
+				item.Title, item.Path = linked.Title, linked.Path
+			} else {
+				item.Title = strings.TrimSpace(string(c.Text(source)))
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// linkItem returns the entry of a link in a manifest. Links to files that are not served become entries without a
+// page.
+func linkItem(link *ast.Link, source []byte, titles map[string]string) NavItem {
+	item := NavItem{Title: string(link.Text(source))}
+	u, err := url.Parse(string(link.Destination))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return item
+	}
+	p := path.Clean(strings.TrimPrefix(u.Path, "/"))
+	candidates := []string{p, p + ".md"}
+	if strings.HasSuffix(u.Path, "/") || p == "." {
+		candidates = nil
+		for _, name := range indexFiles {
+			candidates = append(candidates, path.Join(p, name))
+		}
+	}
+	for _, c := range candidates {
+		if _, ok := titles[c]; ok {
+			item.Path = c
+			break
+		}
+	}
+	if item.Title == "" {
+		item.Title = titles[item.Path]
+	}
+	return item
+}
+
+// fromFolders arranges pages by folder. Each folder comes after the pages next to it, and leads to its index file if
+// it has one, titled as that file.
+func fromFolders(pages []PageLink) []NavItem {
+	type folder struct {
+		item    NavItem
+		folders map[string]*folder
+	}
+	top := &folder{folders: make(map[string]*folder)}
+	for _, p := range pages {
+		f := top
+		parts := strings.Split(p.Path, "/")
+		for _, name := range parts[:len(parts)-1] {
+			sub, ok := f.folders[name]
+			if !ok {
+				sub = &folder{item: NavItem{Title: name}, folders: make(map[string]*folder)}
+				f.folders[name] = sub
+			}
+			f = sub
+		}
+		if isIndex(parts[len(parts)-1]) && f != top && f.item.Path == "" {
+			f.item.Title, f.item.Path = p.Title, p.Path
+			continue
+		}
+		f.item.Children = append(f.item.Children, NavItem{Title: p.Title, Path: p.Path})
+	}
+
+	var build func(f *folder) NavItem
+	build = func(f *folder) NavItem {
+		item := f.item
+		sort.SliceStable(item.Children, func(i, j int) bool {
+			return isIndex(path.Base(item.Children[i].Path)) && !isIndex(path.Base(item.Children[j].Path))
+		})
+		names := make([]string, 0, len(f.folders))
+		for name := range f.folders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			item.Children = append(item.Children, build(f.folders[name]))
+		}
+		return item
+	}
+	return build(top).Children
+}
+
+// isIndex reports whether a file name is that of an index file.
+func isIndex(name string) bool {
+	for _, n := range indexFiles {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Locate marks the page at p in a copy of tree, and returns it with the pages before and after it in reading order.
+func Locate(tree []NavItem, p string) (marked []NavItem, prev, next *PageLink) {
+	var order []PageLink
+	var mark func(items []NavItem) ([]NavItem, bool)
+	mark = func(items []NavItem) ([]NavItem, bool) {
+		copied := make([]NavItem, len(items))
+		open := false
+		for i, item := range items {
+			if item.Path != "" {
+				order = append(order, PageLink{Path: item.Path, Title: item.Title})
+			}
+			item.Current = item.Path == p
+			item.Children, item.Open = mark(item.Children)
+			item.Open = item.Open || item.Current
+			open = open || item.Open
+			copied[i] = item
+		}
+		return copied, open
+	}
+	marked, _ = mark(tree)
+
+	// The first occurrence of the page decides its neighbours
+	for i, page := range order {
+		if page.Path != p {
+			continue
+		}
+		for j := i - 1; j >= 0 && prev == nil; j-- {
+			if order[j].Path != p {
+				prev = &order[j]
+			}
+		}
+		for j := i + 1; j < len(order) && next == nil; j++ {
+			if order[j].Path != p {
+				next = &order[j]
+			}
+		}
+		break
+	}
+	return marked, prev, next
+}
@@ -0,0 +1,230 @@
+package options
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prefix of the environment variables overriding settings, followed by the upper-cased key with - as _
+const envPrefix = "MDS_"
+
+// Names of configuration files, in order of preference
+var fileNames = []string{"mds.yaml", "mds.yml", ".mdsrc"}
+
+// Sources maps the name of every setting to where its value comes from: "default", a configuration file, an
+// environment variable or "flag".
+type Sources map[string]string
+
+// Files returns the configuration files in use, in increasing order of precedence: the one in the mds folder of the
+// user config dir, then the one in workDir, the directory mds was started from.
+func Files(workDir string) []string {
+	var dirs []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(dir, "mds"))
+	}
+	dirs = append(dirs, workDir)
+	var files []string
+	for _, dir := range dirs {
+		for _, name := range fileNames {
+			p := filepath.Join(dir, name)
+			if info, err := os.Stat(p); err == nil && !info.IsDir() {
+				files = append(files, p)
+				break
+			}
+		}
+	}
+	return files
+}
+
+// Load parses args into the flags of fs, and sets the flags not given there from the environment and then from the
+// configuration files, so that flags take precedence over environment variables, which take precedence over the
+// project file in workDir, which takes precedence over the user file. Keys of configuration files are flag names; lists
+// are written as sequences and lists of pairs as mappings. Flags named in commandLineOnly are only read from args.
+func Load(fs *flag.FlagSet, workDir string, args []string, commandLineOnly ...string) (Sources, error) {
+	return load(fs, workDir, args, true, commandLineOnly)
+}
+
+// LoadKnown loads settings as Load does, for modes that only take some of the settings of the server: settings of
+// configuration files that fs does not define are skipped instead of refused.
+func LoadKnown(fs *flag.FlagSet, workDir string, args []string) (Sources, error) {
+	return load(fs, workDir, args, false, nil)
+}
+
+// load implements Load and LoadKnown, refusing settings that fs does not define if strict is set.
+func load(fs *flag.FlagSet, workDir string, args []string, strict bool, commandLineOnly []string) (Sources, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	skip := make(map[string]bool)
+	for _, name := range commandLineOnly {
+		skip[name] = true
+	}
+	sources := make(Sources)
+	fs.VisitAll(func(f *flag.Flag) {
+		if !skip[f.Name] {
+			sources[f.Name] = "default"
+		}
+	})
+	fs.Visit(func(f *flag.Flag) {
+		if !skip[f.Name] {
+			sources[f.Name] = "flag"
+		}
+	})
+	set := func(name, value, source string) error {
+		if sources[name] == "flag" {
+			return nil
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s: %s: %v", source, name, err)
+		}
+		sources[name] = source
+		return nil
+	}
+
+	files := Files(workDir)
+	for i := len(files) - 1; i >= 0; i-- {
+		values, err := readFile(files[i])
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range values {
+			if _, ok := sources[name]; !ok && strict {
+				return nil, fmt.Errorf("%s: unknown setting %q", files[i], name)
+			} else if !ok {
+				continue
+			}
+			if sources[name] != "default" {
+				// Set by a file of higher precedence
+				continue
+			}
+			if err := set(name, value, files[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for name := range sources {
+		env := envPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+		if value, ok := os.LookupEnv(env); ok {
+			if err := set(name, value, env); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sources, nil
+}
+
+// Reread loads the settings again as Load does, and returns their values as text without changing fs.
+func Reread(fs *flag.FlagSet, workDir string, args []string, commandLineOnly ...string) (map[string]string, error) {
+	copied := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	copied.SetOutput(ioutil.Discard)
+	fs.VisitAll(func(f *flag.Flag) {
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		copied.Var(&textValue{text: f.DefValue, isBool: ok && b.IsBoolFlag()}, f.Name, f.Usage)
+	})
+	sources, err := Load(copied, workDir, args, commandLineOnly...)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(sources))
+	for name := range sources {
+		values[name] = copied.Lookup(name).Value.String()
+	}
+	return values, nil
+}
+
+// textValue holds the text of a setting, parsed as a boolean flag if isBool is set.
+type textValue struct {
+	text   string
+	isBool bool
+}
+
+func (v *textValue) String() string     { return v.text }
+func (v *textValue) Set(s string) error { v.text = s; return nil }
+func (v *textValue) IsBoolFlag() bool   { return v.isBool }
+
+// readFile reads a YAML configuration file into the string values of flags.
+func readFile(fileName string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	values := make(map[string]string, len(raw))
+	for name, v := range raw {
+		switch v := v.(type) {
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[name] = strings.Join(items, ",")
+		case map[string]interface{}:
+			values[name] = joinPairs(v)
+		case nil:
+			values[name] = ""
+		default:
+			values[name] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
+}
+
+// Print writes the settings of fs as a configuration file, noting where each value comes from.
+func Print(w io.Writer, fs *flag.FlagSet, sources Sources) {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := fs.Lookup(name).Value
+		text := value.String()
+		if getter, ok := value.(flag.Getter); ok {
+			if _, ok := getter.Get().(string); ok {
+				text = fmt.Sprintf("%q", text)
+			}
+		}
+		fmt.Fprintf(w, "%s: %s # %s\n", name, text, sources[name])
+	}
+}
+
+// SplitPairs parses a comma-separated list of key:value pairs.
+func SplitPairs(s string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		kv := strings.SplitN(item, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%q is not a key:value pair", item)
+		}
+		pairs[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return pairs, nil
+}
+
+// joinPairs writes a mapping as a comma-separated list of key:value pairs, sorted by key.
+func joinPairs(m map[string]interface{}) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]string, len(keys))
+	for i, k := range keys {
+		items[i] = fmt.Sprintf("%s:%v", k, m[k])
+	}
+	return strings.Join(items, ",")
+}
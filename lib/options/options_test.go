@@ -0,0 +1,123 @@
+package options
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name          string
+		user, project string // configuration files, left out if empty
+		env           map[string]string
+		args          []string
+		known         bool // LoadKnown instead of Load
+		want          map[string]string
+		sources       map[string]string
+		err           string
+	}{
+		{name: "defaults",
+			want:    map[string]string{"port": "8080", "dark": "false", "exts": ""},
+			sources: map[string]string{"port": "default", "dark": "default", "exts": "default"}},
+		{name: "user file", user: "port: 7000\ndark: true",
+			want:    map[string]string{"port": "7000", "dark": "true"},
+			sources: map[string]string{"port": "user", "dark": "user"}},
+		{name: "project file over user file", user: "port: 7000\ndark: true", project: "port: 7001",
+			want:    map[string]string{"port": "7001", "dark": "true"},
+			sources: map[string]string{"port": "project", "dark": "user"}},
+		{name: "environment over project file", user: "port: 7000", project: "port: 7001",
+			env:     map[string]string{"MDS_PORT": "7002"},
+			want:    map[string]string{"port": "7002"},
+			sources: map[string]string{"port": "MDS_PORT"}},
+		{name: "flags over environment", project: "port: 7001", env: map[string]string{"MDS_PORT": "7002"},
+			args:    []string{"--port", "7003"},
+			want:    map[string]string{"port": "7003"},
+			sources: map[string]string{"port": "flag"}},
+		{name: "keys with dashes", env: map[string]string{"MDS_API_TOKEN": "secret"},
+			want:    map[string]string{"api-token": "secret"},
+			sources: map[string]string{"api-token": "MDS_API_TOKEN"}},
+		{name: "lists", project: "exts: [.md, .txt]",
+			want: map[string]string{"exts": ".md,.txt"}},
+		{name: "unknown key", user: "port: 7000", project: "colour: blue", err: `unknown setting "colour"`},
+		{name: "unknown key in the user file", user: "colour: blue", err: `unknown setting "colour"`},
+		{name: "command line only key", project: "file: README.md", err: `unknown setting "file"`},
+		{name: "unknown key skipped", project: "colour: blue\nport: 7001", known: true,
+			want: map[string]string{"port": "7001"}},
+		{name: "invalid value", env: map[string]string{"MDS_DARK": "maybe"}, err: "MDS_DARK: dark"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, workDir := t.TempDir(), t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", config)
+			t.Setenv("HOME", config)
+			files := map[string]string{"user": "", "project": ""}
+			if tt.user != "" {
+				files["user"] = writeConfig(t, filepath.Join(config, "mds"), tt.user)
+			}
+			if tt.project != "" {
+				files["project"] = writeConfig(t, workDir, tt.project)
+			}
+			// Variables set outside the test are restored when it ends
+			for _, name := range []string{"MDS_PORT", "MDS_DARK", "MDS_EXTS", "MDS_API_TOKEN", "MDS_FILE"} {
+				t.Setenv(name, "")
+				os.Unsetenv(name)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			fs := flag.NewFlagSet("mds", flag.ContinueOnError)
+			fs.String("port", "8080", "")
+			fs.Bool("dark", false, "")
+			fs.String("exts", "", "")
+			fs.String("api-token", "", "")
+			fs.String("file", "", "")
+			var sources Sources
+			var err error
+			if tt.known {
+				sources, err = LoadKnown(fs, workDir, tt.args)
+			} else {
+				sources, err = Load(fs, workDir, tt.args, "file")
+			}
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.want {
+				if got := fs.Lookup(name).Value.String(); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			for name, want := range tt.sources {
+				if file, ok := files[want]; ok {
+					want = file
+				}
+				if got := sources[name]; got != want {
+					t.Errorf("%s from %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// writeConfig writes a configuration file in dir and returns its path.
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, "mds.yaml")
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
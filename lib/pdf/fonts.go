@@ -0,0 +1,114 @@
+package pdf
+
+import "unicode/utf8"
+
+// Standard fonts, which every PDF viewer provides so that nothing needs to be embedded, indexed by style: monospace
+// adds 4, bold 2 and italic 1.
+var fontNames = [8]string{
+	"Helvetica", "Helvetica-Oblique", "Helvetica-Bold", "Helvetica-BoldOblique",
+	"Courier", "Courier-Oblique", "Courier-Bold", "Courier-BoldOblique",
+}
+
+// Advance widths of the printable ASCII characters, in thousandths of the font size
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// Windows-1252 codes of the characters outside Latin-1 that the standard fonts can show
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a,
+	'‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// Widths of the Windows-1252 punctuation that differs from the width of its neighbours, regular and bold
+var punctuationWidths = map[byte][2]int{
+	0x85: {1000, 1000}, 0x91: {222, 278}, 0x92: {222, 278}, 0x93: {333, 500}, 0x94: {333, 500}, 0x95: {350, 350},
+	0x96: {556, 556}, 0x97: {1000, 1000}, 0x99: {1000, 1000}, 0xa0: {278, 278},
+}
+
+// font returns the index of the font for a span.
+func font(s *Span) int {
+	i := 0
+	if s.Mono {
+		i += 4
+	}
+	if s.Bold {
+		i += 2
+	}
+	if s.Italic {
+		i++
+	}
+	return i
+}
+
+// charWidth returns the width of a Windows-1252 character in the given font, in thousandths of the font size.
+func charWidth(c byte, f int) int {
+	bold := f&2 != 0
+	switch {
+	case f >= 4:
+		return 600
+	case c >= 32 && c < 127:
+		if bold {
+			return helveticaBoldWidths[c-32]
+		}
+		return helveticaWidths[c-32]
+	}
+	if w, ok := punctuationWidths[c]; ok {
+		if bold {
+			return w[1]
+		}
+		return w[0]
+	}
+	switch {
+	case c >= 0xc0 && c < 0xdf:
+		// Accented capitals
+		return 722
+	case bold:
+		return 611
+	}
+	return 556
+}
+
+// textWidth returns the width in points of encoded text set in the font of s.
+func textWidth(text []byte, s *Span) float64 {
+	f := font(s)
+	w := 0
+	for _, c := range text {
+		w += charWidth(c, f)
+	}
+	return float64(w) * s.Size / 1000
+}
+
+// encode converts UTF-8 text to Windows-1252, the encoding of the standard fonts. Characters it lacks become '?'.
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		switch {
+		case r < 0x80 || r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		case winAnsi[r] != 0:
+			out = append(out, winAnsi[r])
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
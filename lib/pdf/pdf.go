@@ -0,0 +1,660 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Geometry of an A4 page and its margins, in points
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	margin       = 56.69
+	contentWidth = pageWidth - 2*margin
+	top          = pageHeight - margin
+)
+
+// Layout parameters: default line height as a multiple of the text size, padding of table cells and filled blocks,
+// the space above footnotes, and the width reserved for text aligned right
+const (
+	leading     = 1.35
+	cellPadding = 4.0
+	noteGap     = 10.0
+	rightWidth  = 36.0
+)
+
+// Colours of page decorations
+var (
+	ruleColor   = Color{0.8, 0.8, 0.8}
+	mutedColor  = Color{0.45, 0.45, 0.45}
+	headerColor = Color{0.94, 0.94, 0.94}
+)
+
+// Color is an RGB colour, with components between 0 and 1.
+type Color struct {
+	R, G, B float64
+}
+
+// Span is a run of text in a single style. Link is a URI, or # followed by an anchor for a place in the document.
+// Rise lifts the text above the baseline, for superscripts. Note is a footnote, printed at the bottom of the page the
+// span ends up on.
+type Span struct {
+	Text   string
+	Size   float64
+	Bold   bool
+	Italic bool
+	Mono   bool
+	Color  Color
+	Link   string
+	Strike bool
+	Rise   float64
+	Note   []Span
+}
+
+// Block sets how a paragraph is laid out. Indent is measured from the left margin, as are Bars, the positions of
+// vertical lines drawn along the paragraph, as used for quotes. Keep is the space needed below the first line to keep
+// a heading on the same page as what follows. Marker, such as a bullet, goes left of the first line. Anchor names the
+// top of the paragraph as a link target. Fill is the background of every line. Pre keeps line breaks and spaces,
+// breaking long lines anywhere. Leading is the line height as a multiple of the text size. Right is drawn aligned to
+// the right margin next to the last line.
+type Block struct {
+	Indent  float64
+	Before  float64
+	After   float64
+	Leading float64
+	Keep    float64
+	Marker  []Span
+	Anchor  string
+	Fill    *Color
+	Bars    []float64
+	Pre     bool
+	Right   string
+}
+
+// Align is the horizontal alignment of a table column.
+type Align int
+
+// Alignments of table columns
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// fragment is text in one style, placed on a line.
+type fragment struct {
+	span  *Span
+	text  []byte
+	x     float64
+	width float64
+}
+
+// line is a laid out line of text.
+type line struct {
+	frags []fragment
+	width float64
+	size  float64
+}
+
+// add appends text in the style of s to the line, after a space if space is set.
+func (l *line) add(s *Span, text []byte, space bool) {
+	w := textWidth(text, s)
+	gap := 0.0
+	if space && len(l.frags) > 0 {
+		gap = textWidth([]byte{' '}, s)
+	}
+	if n := len(l.frags); n > 0 && l.frags[n-1].span == s {
+		// Same style: extend the last fragment
+		f := &l.frags[n-1]
+		if gap > 0 {
+			f.text = append(f.text, ' ')
+		}
+		f.text = append(f.text, text...)
+		f.width += gap + w
+	} else {
+		l.frags = append(l.frags, fragment{span: s, text: append([]byte(nil), text...), x: l.width + gap, width: w})
+	}
+	l.width += gap + w
+	if s.Size > l.size {
+		l.size = s.Size
+	}
+}
+
+// annotation is a link area on a page.
+type annotation struct {
+	x0, y0, x1, y1 float64
+	target         string
+}
+
+// page is a page being laid out.
+type page struct {
+	content bytes.Buffer
+	links   []annotation
+	notes   []line
+	noteH   float64
+}
+
+// dest is a place in the document.
+type dest struct {
+	page int
+	y    float64
+}
+
+// bookmark is an entry of the document outline.
+type bookmark struct {
+	title  string
+	level  int
+	anchor string
+}
+
+// Document lays out text, images and tables on A4 pages, and writes them as a PDF file.
+type Document struct {
+	Title     string
+	pages     []*page
+	y         float64
+	bottom    float64
+	space     float64
+	anchors   map[string]dest
+	bookmarks []bookmark
+	images    []image.Image
+}
+
+// New creates an empty document.
+func New(title string) *Document {
+	d := &Document{Title: title, anchors: make(map[string]dest)}
+	d.newPage()
+	return d
+}
+
+// page returns the current page.
+func (d *Document) page() *page {
+	return d.pages[len(d.pages)-1]
+}
+
+// newPage starts a new page, on which nothing has been placed yet.
+func (d *Document) newPage() {
+	if len(d.pages) > 0 {
+		d.finishPage()
+	}
+	d.pages = append(d.pages, &page{})
+	d.y, d.bottom, d.space = top, margin, 0
+}
+
+// finishPage draws the footnotes of the current page at its bottom.
+func (d *Document) finishPage() {
+	p := d.page()
+	if len(p.notes) == 0 {
+		return
+	}
+	y := margin + p.noteH
+	fmt.Fprintf(&p.content, "%s 0.5 w %.2f %.2f m %.2f %.2f l S\n", strokeColor(ruleColor), margin, y-noteGap/2,
+		margin+contentWidth/4, y-noteGap/2)
+	y -= noteGap
+	for _, l := range p.notes {
+		h := l.size * leading
+		d.drawLine(l, margin, y, h)
+		y -= h
+	}
+	p.notes = nil
+}
+
+// fits reports whether h points are left on the current page, moving to a new page if not. A page that is still
+// empty always fits, since what does not fit there fits nowhere.
+func (d *Document) fits(h float64) bool {
+	if d.y == top || d.y-h >= d.bottom {
+		return true
+	}
+	d.newPage()
+	return false
+}
+
+// gap returns the space to leave before a block, which is dropped at the top of a page.
+func (d *Document) gap(before float64) float64 {
+	if d.y == top {
+		return 0
+	}
+	if before > d.space {
+		return before
+	}
+	return d.space
+}
+
+// Paragraph lays out spans as a paragraph, starting new pages as needed.
+func (d *Document) Paragraph(spans []Span, b Block) {
+	width := contentWidth - b.Indent
+	if b.Right != "" {
+		width -= rightWidth
+	}
+	var lines []line
+	if b.Pre {
+		lines = wrapPre(spans, width)
+	} else {
+		lines = wrap(spans, width)
+	}
+	if b.Leading == 0 {
+		b.Leading = leading
+	}
+
+	gap := d.gap(b.Before)
+	for i, l := range lines {
+		h := l.size * b.Leading
+		notes := d.notes(l)
+		need := gap + h + notesHeight(notes, d.page())
+		if i == 0 {
+			need += b.Keep
+		}
+		if !d.fits(need) {
+			gap = 0
+		}
+		p := d.page()
+		noteH := notesHeight(notes, p)
+		x := margin + b.Indent
+		for _, bar := range b.Bars {
+			fmt.Fprintf(&p.content, "%s 2 w %.2f %.2f m %.2f %.2f l S\n", strokeColor(ruleColor), margin+bar, d.y,
+				margin+bar, d.y-gap-h)
+		}
+		d.y -= gap
+		gap = 0
+		if b.Anchor != "" && i == 0 {
+			d.anchors[b.Anchor] = dest{page: len(d.pages), y: d.y}
+		}
+		if b.Fill != nil {
+			fmt.Fprintf(&p.content, "%s %.2f %.2f %.2f %.2f re f\n", fillColor(*b.Fill), x-cellPadding, d.y-h,
+				contentWidth-b.Indent+cellPadding, h)
+		}
+		if i == 0 && len(b.Marker) > 0 {
+			if marker := wrap(b.Marker, contentWidth); len(marker) > 0 {
+				d.drawLine(marker[0], x-marker[0].width-6, d.y, h)
+			}
+		}
+		d.drawLine(l, x, d.y, h)
+		if i == len(lines)-1 && b.Right != "" {
+			right := wrap([]Span{{Text: b.Right, Size: l.size, Color: mutedColor}}, contentWidth)
+			if len(right) > 0 {
+				d.drawLine(right[0], margin+contentWidth-right[0].width, d.y, h)
+			}
+		}
+		d.y -= h
+		p.notes = append(p.notes, notes...)
+		p.noteH += noteH
+		d.bottom += noteH
+	}
+	d.space = b.After
+}
+
+// notes lays out the footnotes of the spans on a line.
+func (d *Document) notes(l line) []line {
+	var notes []line
+	var last *Span
+	for _, f := range l.frags {
+		if f.span.Note != nil && f.span != last {
+			notes = append(notes, wrap(f.span.Note, contentWidth)...)
+		}
+		last = f.span
+	}
+	return notes
+}
+
+// notesHeight returns the space taken by footnotes added to p, including the gap above the first footnote of a page.
+func notesHeight(notes []line, p *page) float64 {
+	h := 0.0
+	for _, n := range notes {
+		h += n.size * leading
+	}
+	if h > 0 && len(p.notes) == 0 {
+		h += noteGap
+	}
+	return h
+}
+
+// drawLine draws a line of text whose top is at y, in a line box of height h.
+func (d *Document) drawLine(l line, x, y, h float64) {
+	p := d.page()
+	baseline := y - h/2 - 0.28*l.size
+	for _, f := range l.frags {
+		s := f.span
+		fx := x + f.x
+		fmt.Fprintf(&p.content, "BT /F%d %.2f Tf %s %.2f %.2f Td (%s) Tj ET\n", font(s), s.Size, fillColor(s.Color), fx,
+			baseline+s.Rise, escape(f.text))
+		if s.Strike {
+			sy := baseline + s.Rise + 0.3*s.Size
+			fmt.Fprintf(&p.content, "%s 0.6 w %.2f %.2f m %.2f %.2f l S\n", strokeColor(s.Color), fx, sy, fx+f.width, sy)
+		}
+		if s.Link != "" {
+			p.links = append(p.links, annotation{fx, baseline + s.Rise - 0.22*s.Size, fx + f.width,
+				baseline + s.Rise + 0.8*s.Size, s.Link})
+		}
+	}
+}
+
+// Rule draws a horizontal line across the page.
+func (d *Document) Rule() {
+	gap := d.gap(8)
+	d.fits(gap + 1)
+	d.y -= gap
+	fmt.Fprintf(&d.page().content, "%s 0.8 w %.2f %.2f m %.2f %.2f l S\n", strokeColor(ruleColor), margin, d.y,
+		margin+contentWidth, d.y)
+	d.space = 8
+}
+
+// PageBreak starts a new page, unless the current one is still empty.
+func (d *Document) PageBreak() {
+	if d.y != top {
+		d.newPage()
+	}
+}
+
+// Image draws img below the last block, at 96 pixels per inch and scaled down to fit the page.
+func (d *Document) Image(img image.Image, indent float64) {
+	bounds := img.Bounds()
+	w, h := float64(bounds.Dx())*0.75, float64(bounds.Dy())*0.75
+	if w == 0 || h == 0 {
+		return
+	}
+	if limit := contentWidth - indent; w > limit {
+		w, h = limit, h*limit/w
+	}
+	if limit := top - margin; h > limit {
+		w, h = w*limit/h, limit
+	}
+	gap := d.gap(8)
+	if !d.fits(gap + h) {
+		gap = 0
+	}
+	d.y -= gap + h
+	d.images = append(d.images, img)
+	fmt.Fprintf(&d.page().content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, margin+indent, d.y,
+		len(d.images)-1)
+	d.space = 8
+}
+
+// Table draws a table whose first header rows are shaded and repeated on every page it spans.
+func (d *Document) Table(rows [][][]Span, header int, aligns []Align, indent float64) {
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if columns == 0 {
+		return
+	}
+
+	// Columns get their natural width. If the table is too wide, columns give up the room they have beyond their
+	// longest word, in proportion
+	widths := make([]float64, columns)
+	least := make([]float64, columns)
+	for _, row := range rows {
+		for c, cell := range row {
+			w := 2*cellPadding + 1
+			for _, l := range wrap(cell, 1e6) {
+				w += l.width
+			}
+			widths[c] = math.Max(widths[c], w)
+			least[c] = math.Max(least[c], 2*cellPadding+1+longestWord(cell))
+		}
+	}
+	total, minimum, avail := 0.0, 0.0, contentWidth-indent
+	for c := range widths {
+		total += widths[c]
+		minimum += least[c]
+	}
+	for c := range widths {
+		switch {
+		case total <= avail:
+		case minimum < avail:
+			widths[c] = least[c] + (widths[c]-least[c])*(avail-minimum)/(total-minimum)
+		default:
+			widths[c] = least[c] * avail / minimum
+		}
+	}
+
+	// Rows are never split across pages
+	cells := make([][][]line, len(rows))
+	heights := make([]float64, len(rows))
+	for r, row := range rows {
+		cells[r] = make([][]line, columns)
+		for c := range cells[r] {
+			if c < len(row) {
+				cells[r][c] = wrap(row[c], widths[c]-2*cellPadding)
+			}
+			h := 2 * cellPadding
+			for _, l := range cells[r][c] {
+				h += l.size * leading
+			}
+			if h > heights[r] {
+				heights[r] = h
+			}
+		}
+	}
+	d.y -= d.gap(8)
+	for r := range rows {
+		if !d.fits(heights[r]) && r >= header {
+			for h := 0; h < header; h++ {
+				d.tableRow(cells[h], heights[h], widths, aligns, indent, true)
+			}
+		}
+		d.tableRow(cells[r], heights[r], widths, aligns, indent, r < header)
+	}
+	d.space = 8
+}
+
+// tableRow draws a row of laid out table cells.
+func (d *Document) tableRow(cells [][]line, height float64, widths []float64, aligns []Align, indent float64,
+	shaded bool) {
+	p := d.page()
+	x := margin + indent
+	for c, lines := range cells {
+		if shaded {
+			fmt.Fprintf(&p.content, "%s %.2f %.2f %.2f %.2f re f\n", fillColor(headerColor), x, d.y-height, widths[c],
+				height)
+		}
+		fmt.Fprintf(&p.content, "%s 0.5 w %.2f %.2f %.2f %.2f re S\n", strokeColor(ruleColor), x, d.y-height,
+			widths[c], height)
+		y := d.y - cellPadding
+		for _, l := range lines {
+			lx := x + cellPadding
+			if c < len(aligns) {
+				switch aligns[c] {
+				case AlignCenter:
+					lx = x + (widths[c]-l.width)/2
+				case AlignRight:
+					lx = x + widths[c] - cellPadding - l.width
+				}
+			}
+			h := l.size * leading
+			d.drawLine(l, lx, y, h)
+			y -= h
+		}
+		x += widths[c]
+	}
+	d.y -= height
+}
+
+// Bookmark adds an entry for the paragraph with the given anchor to the outline shown by PDF viewers.
+func (d *Document) Bookmark(title string, level int, anchor string) {
+	d.bookmarks = append(d.bookmarks, bookmark{title, level, anchor})
+}
+
+// PageOf returns the number of the page holding anchor, counting from 1, or 0 if there is no such anchor.
+func (d *Document) PageOf(anchor string) int {
+	return d.anchors[anchor].page
+}
+
+// Pages returns the number of pages laid out so far.
+func (d *Document) Pages() int {
+	return len(d.pages)
+}
+
+// box is a word, or the part of a word in a single style.
+type box struct {
+	span  *Span
+	text  []byte
+	space bool
+	brk   bool
+}
+
+// boxes splits spans into words. Runs of white space separate words, and line feeds force a line break. Consecutive
+// spans in the same style share it, so that their text can be drawn at once.
+func boxes(spans []Span) []box {
+	var out []box
+	var last *Span
+	space, brk := false, false
+	for i := range spans {
+		s := &spans[i]
+		text := encode(s.Text)
+		if last != nil && sameStyle(last, s) {
+			s = last
+		}
+		last = s
+		start := -1
+		flush := func(end int) {
+			if start >= 0 {
+				out = append(out, box{span: s, text: text[start:end], space: space, brk: brk})
+				space, brk, start = false, false, -1
+			}
+		}
+		for j, c := range text {
+			switch c {
+			case ' ', '\t', '\r':
+				flush(j)
+				space = true
+			case '\n':
+				flush(j)
+				brk = true
+			default:
+				if start < 0 {
+					start = j
+				}
+			}
+		}
+		flush(len(text))
+	}
+	return out
+}
+
+// longestWord returns the width of the widest word in spans.
+func longestWord(spans []Span) float64 {
+	longest, w := 0.0, 0.0
+	for _, b := range boxes(spans) {
+		if b.space || b.brk {
+			w = 0
+		}
+		w += textWidth(b.text, b.span)
+		longest = math.Max(longest, w)
+	}
+	return longest
+}
+
+// sameStyle reports whether a and b are drawn alike. Spans with footnotes never are, as each has its own.
+func sameStyle(a, b *Span) bool {
+	return a.Size == b.Size && a.Bold == b.Bold && a.Italic == b.Italic && a.Mono == b.Mono && a.Color == b.Color &&
+		a.Link == b.Link && a.Strike == b.Strike && a.Rise == b.Rise && a.Note == nil && b.Note == nil
+}
+
+// wrap breaks spans into lines no wider than width, between words where possible.
+func wrap(spans []Span, width float64) []line {
+	var lines []line
+	var cur line
+	bs := boxes(spans)
+	for i := 0; i < len(bs); {
+		// A word may consist of several boxes not separated by spaces
+		j, w := i+1, textWidth(bs[i].text, bs[i].span)
+		for ; j < len(bs) && !bs[j].space && !bs[j].brk; j++ {
+			w += textWidth(bs[j].text, bs[j].span)
+		}
+		space := 0.0
+		if bs[i].space && len(cur.frags) > 0 {
+			space = textWidth([]byte{' '}, bs[i].span)
+		}
+		if bs[i].brk && (len(cur.frags) > 0 || len(lines) > 0) || len(cur.frags) > 0 && cur.width+space+w > width {
+			lines = append(lines, cur)
+			cur = line{}
+		}
+
+		for k := i; k < j; k++ {
+			b := bs[k]
+			if w <= width || textWidth(b.text, b.span) <= width-cur.width {
+				cur.add(b.span, b.text, k == i && b.space)
+				continue
+			}
+			// Too long for any line: break it anywhere
+			for c := range b.text {
+				if cw := textWidth(b.text[c:c+1], b.span); len(cur.frags) > 0 && cur.width+cw > width {
+					lines = append(lines, cur)
+					cur = line{}
+				}
+				cur.add(b.span, b.text[c:c+1], false)
+			}
+		}
+		i = j
+	}
+	if len(cur.frags) > 0 {
+		lines = append(lines, cur)
+	}
+	return lines
+}
+
+// wrapPre breaks spans into lines at every line feed, and anywhere lines are wider than width. Tabs become four
+// spaces.
+func wrapPre(spans []Span, width float64) []line {
+	var lines []line
+	var cur line
+	for i := range spans {
+		s := &spans[i]
+		if s.Size > cur.size {
+			cur.size = s.Size
+		}
+		for _, c := range encode(s.Text) {
+			switch c {
+			case '\n':
+				lines = append(lines, cur)
+				cur = line{size: s.Size}
+				continue
+			case '\t':
+				for n := 0; n < 4; n++ {
+					cur.add(s, []byte{' '}, false)
+				}
+				continue
+			}
+			if cw := textWidth([]byte{c}, s); len(cur.frags) > 0 && cur.width+cw > width {
+				lines = append(lines, cur)
+				cur = line{size: s.Size}
+			}
+			cur.add(s, []byte{c}, false)
+		}
+	}
+	if len(cur.frags) > 0 {
+		lines = append(lines, cur)
+	}
+	return lines
+}
+
+// fillColor returns the operator setting the fill colour, which is also that of text.
+func fillColor(c Color) string {
+	return fmt.Sprintf("%.3f %.3f %.3f rg", c.R, c.G, c.B)
+}
+
+// strokeColor returns the operator setting the colour of lines.
+func strokeColor(c Color) string {
+	return fmt.Sprintf("%.3f %.3f %.3f RG", c.R, c.G, c.B)
+}
+
+// rgb returns the pixels of img as RGB bytes, blended onto white where they are transparent.
+func rgb(img image.Image) []byte {
+	bounds := img.Bounds()
+	out := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			a := int(c.A)
+			for _, v := range []uint8{c.R, c.G, c.B} {
+				out = append(out, byte((int(v)*a+255*(255-a))/255))
+			}
+		}
+	}
+	return out
+}
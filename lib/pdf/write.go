@@ -0,0 +1,232 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// Size of page numbers, and their distance from the bottom edge
+const (
+	footerSize = 8.0
+	footerY    = margin / 2
+)
+
+// objects collects the numbered objects of a PDF file.
+type objects struct {
+	bodies [][]byte
+}
+
+// alloc reserves the number of a new object.
+func (o *objects) alloc() int {
+	o.bodies = append(o.bodies, nil)
+	return len(o.bodies)
+}
+
+// set sets the body of object n.
+func (o *objects) set(n int, format string, args ...interface{}) {
+	o.bodies[n-1] = []byte(fmt.Sprintf(format, args...))
+}
+
+// stream sets object n to a compressed stream, described by the entries of dict.
+func (o *objects) stream(n int, dict string, data []byte) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(data)
+	zw.Close()
+	o.set(n, "<< %s /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream", dict, compressed.Len(),
+		compressed.Bytes())
+}
+
+// outlineItem is a bookmark in the outline tree.
+type outlineItem struct {
+	bookmark
+	obj      int
+	children []*outlineItem
+}
+
+// Write finishes the last page and writes the document to w as a PDF file.
+func (d *Document) Write(w io.Writer) error {
+	d.finishPage()
+	var o objects
+	catalog, pagesRoot, info := o.alloc(), o.alloc(), o.alloc()
+
+	var resources strings.Builder
+	resources.WriteString("<< /Font <<")
+	for i, name := range fontNames {
+		n := o.alloc()
+		o.set(n, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name)
+		fmt.Fprintf(&resources, " /F%d %d 0 R", i, n)
+	}
+	resources.WriteString(" >> /XObject <<")
+	for i, img := range d.images {
+		n := o.alloc()
+		bounds := img.Bounds()
+		o.stream(n, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB "+
+			"/BitsPerComponent 8", bounds.Dx(), bounds.Dy()), rgb(img))
+		fmt.Fprintf(&resources, " /Im%d %d 0 R", i, n)
+	}
+	resources.WriteString(" >> >>")
+
+	pageObjs := make([]int, len(d.pages))
+	for i := range d.pages {
+		pageObjs[i] = o.alloc()
+	}
+	destination := func(anchor string) (string, bool) {
+		dest, ok := d.anchors[anchor]
+		if !ok {
+			return "", false
+		}
+		return fmt.Sprintf("[%d 0 R /XYZ 0 %.2f null]", pageObjs[dest.page-1], dest.y), true
+	}
+
+	var kids strings.Builder
+	for i, p := range d.pages {
+		// Page numbers are only known now
+		number := encode(fmt.Sprintf("%d / %d", i+1, len(d.pages)))
+		numberSpan := &Span{Size: footerSize}
+		content := append([]byte(nil), p.content.Bytes()...)
+		content = append(content, fmt.Sprintf("BT /F0 %.2f Tf %s %.2f %.2f Td (%s) Tj ET\n", footerSize,
+			fillColor(mutedColor), (pageWidth-textWidth(number, numberSpan))/2, footerY, escape(number))...)
+		contentObj := o.alloc()
+		o.stream(contentObj, "", content)
+
+		var annots strings.Builder
+		for _, a := range p.links {
+			action := fmt.Sprintf("/A << /S /URI /URI (%s) >>", escape([]byte(asciiURI(a.target))))
+			if strings.HasPrefix(a.target, "#") {
+				dest, ok := destination(a.target[1:])
+				if !ok {
+					continue
+				}
+				action = "/Dest " + dest
+			}
+			n := o.alloc()
+			o.set(n, "<< /Type /Annot /Subtype /Link /Rect [%.2f %.2f %.2f %.2f] /Border [0 0 0] %s >>", a.x0, a.y0,
+				a.x1, a.y1, action)
+			fmt.Fprintf(&annots, " %d 0 R", n)
+		}
+		o.set(pageObjs[i], "<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources %s /Contents %d 0 R "+
+			"/Annots [%s ] >>", pagesRoot, pageWidth, pageHeight, resources.String(), contentObj, annots.String())
+		fmt.Fprintf(&kids, " %d 0 R", pageObjs[i])
+	}
+	o.set(pagesRoot, "<< /Type /Pages /Kids [%s ] /Count %d >>", kids.String(), len(d.pages))
+
+	// Bookmarks nest below the closest preceding bookmark of a higher level
+	root := &outlineItem{}
+	stack := []*outlineItem{root}
+	for _, b := range d.bookmarks {
+		if _, ok := d.anchors[b.anchor]; !ok {
+			continue
+		}
+		for len(stack) > 1 && stack[len(stack)-1].level >= b.level {
+			stack = stack[:len(stack)-1]
+		}
+		item := &outlineItem{bookmark: b, obj: o.alloc()}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, item)
+		stack = append(stack, item)
+	}
+	outlines := ""
+	if len(root.children) > 0 {
+		root.obj = o.alloc()
+		o.set(root.obj, "<< /Type /Outlines %s >>", writeOutline(&o, root, destination))
+		outlines = fmt.Sprintf(" /Outlines %d 0 R /PageMode /UseOutlines", root.obj)
+	}
+
+	o.set(catalog, "<< /Type /Catalog /Pages %d 0 R%s >>", pagesRoot, outlines)
+	o.set(info, "<< /Title %s /Producer (mds) >>", textString(d.Title))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(o.bodies))
+	for i, body := range o.bodies {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(o.bodies)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(o.bodies)+1,
+		catalog, info, xref)
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// writeOutline sets the objects of the children of item, and returns the entries linking item to them.
+func writeOutline(o *objects, item *outlineItem, destination func(anchor string) (string, bool)) string {
+	count := 0
+	for i, child := range item.children {
+		var links strings.Builder
+		fmt.Fprintf(&links, "/Parent %d 0 R", item.obj)
+		if i > 0 {
+			fmt.Fprintf(&links, " /Prev %d 0 R", item.children[i-1].obj)
+		}
+		if i < len(item.children)-1 {
+			fmt.Fprintf(&links, " /Next %d 0 R", item.children[i+1].obj)
+		}
+		dest, _ := destination(child.anchor)
+		o.set(child.obj, "<< /Title %s %s %s /Dest %s >>", textString(child.title), links.String(),
+			writeOutline(o, child, destination), dest)
+		count += 1 + descendants(child)
+	}
+	if len(item.children) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("/First %d 0 R /Last %d 0 R /Count %d", item.children[0].obj,
+		item.children[len(item.children)-1].obj, count)
+}
+
+// descendants counts the items below item in the outline tree.
+func descendants(item *outlineItem) int {
+	n := 0
+	for _, child := range item.children {
+		n += 1 + descendants(child)
+	}
+	return n
+}
+
+// escape escapes text for a literal string.
+func escape(text []byte) []byte {
+	var out []byte
+	for _, c := range text {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			out = append(out, '\\', c)
+		case c < 32:
+			out = append(out, fmt.Sprintf("\\%03o", c)...)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// textString encodes s as a hexadecimal UTF-16 string, which may hold any character.
+func textString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// asciiURI percent-encodes the bytes of uri that are not printable ASCII, as URIs in PDF files must be.
+func asciiURI(uri string) string {
+	var b strings.Builder
+	for i := 0; i < len(uri); i++ {
+		if c := uri[i]; c <= ' ' || c >= 0x7f {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
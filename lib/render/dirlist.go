@@ -0,0 +1,112 @@
+package render
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	. "github.com/dienakakim/mds/lib/structs"
+)
+
+// Files served in place of a directory listing, in order of preference
+var indexFiles = []string{"README.md", "index.md"}
+
+// listingTemplate renders the body of a directory listing.
+var listingTemplate = template.Must(template.New("listing").Parse(`<h1>Index of {{.Title}}</h1>
+<ul class="list-none">
+{{- if .Parent}}
+    <li class="py-1"><a href="../">../</a></li>
+{{- end}}
+{{- range .Entries}}
+    {{- if .IsDir}}
+    <li class="py-1"><a href="{{.Link}}/">{{.Name}}/</a></li>
+    {{- else if .Markdown}}
+    <li class="py-1 font-bold"><a href="{{.Link}}">{{.Name}}</a></li>
+    {{- else}}
+    <li class="py-1 opacity-75"><a href="{{.Link}}">{{.Name}}</a></li>
+    {{- end}}
+{{- end}}
+</ul>
+`))
+
+// listingEntry is a single file or folder in a directory listing.
+type listingEntry struct {
+	Name     string
+	Link     string
+	IsDir    bool
+	Markdown bool
+}
+
+// indexFile returns the index file inside dir that resolve allows, if there is one.
+func indexFile(dir string, resolve Resolver) (string, bool) {
+	for _, name := range indexFiles {
+		candidate, err := resolve(filepath.ToSlash(filepath.Join(dir, name)))
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// renderDirectory writes a themed listing of the folders and files inside config.FileName. Only the entries that
+// resolve allows are listed, leaving out files of other types and symlinks leading outside the root.
+func renderDirectory(w http.ResponseWriter, r *http.Request, templ *template.Template, config Config,
+	resolve Resolver) {
+	infos, err := ioutil.ReadDir(config.FileName)
+	if err != nil {
+		notFound(w, config.FileName)
+		return
+	}
+
+	// Folders first, then files, each alphabetically
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].IsDir() != infos[j].IsDir() {
+			return infos[i].IsDir()
+		}
+		return strings.ToLower(infos[i].Name()) < strings.ToLower(infos[j].Name())
+	})
+	entries := make([]listingEntry, 0, len(infos))
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+		if _, err := resolve(filepath.ToSlash(filepath.Join(config.FileName, info.Name()))); err != nil {
+			continue
+		}
+		entries = append(entries, listingEntry{
+			Name:     info.Name(),
+			Link:     (&url.URL{Path: info.Name()}).EscapedPath(),
+			IsDir:    info.IsDir(),
+			Markdown: !info.IsDir() && strings.HasSuffix(info.Name(), ".md"),
+		})
+	}
+
+	title := path.Clean("/"+filepath.ToSlash(config.FileName)) + "/"
+	if title == "//" {
+		title = "/"
+	}
+	var body bytes.Buffer
+	listing := struct {
+		Title   string
+		Parent  bool
+		Entries []listingEntry
+	}{Title: title, Parent: title != "/", Entries: entries}
+	if err := listingTemplate.Execute(&body, listing); err != nil {
+		log.Println(err)
+	}
+	rendered := RenderedHTML{Body: template.HTML(body.String()), Style: config.Style, FileName: title,
+		Title: title, Path: filepath.ToSlash(config.FileName), Theme: config.Theme, Dark: config.DarkMode,
+		Search: config.Search, Control: config.Control, Served: config.Served}
+	templ.Execute(w, rendered)
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dienakakim/mds/lib/resolve"
+	. "github.com/dienakakim/mds/lib/structs"
+)
+
+func TestRenderDirectoryListsResolvableEntries(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"root/a.md", "root/secret.txt", "root/sub/b.md", "outside/README.md"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte("outside-content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range map[string]string{
+		"root/etclink":   filepath.Join(dir, "outside"),
+		"root/README.md": filepath.Join(dir, "outside", "README.md"),
+		"root/alias.md":  "a.md",
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(dir, "root")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	root, err := resolve.New(".", []string{".md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	templ := template.Must(template.New("page").Parse("{{.Body}}"))
+	w := httptest.NewRecorder()
+	Render(w, httptest.NewRequest(http.MethodGet, "/", nil), nil, templ, Config{FileName: "."}, nil, nil, root.Resolve)
+	body := w.Body.String()
+	if strings.Contains(body, "outside-content") {
+		t.Fatal("README.md leading outside the root served as the index file")
+	}
+	for _, name := range []string{"a.md", "alias.md", "sub/"} {
+		if !strings.Contains(body, ">"+name+"<") {
+			t.Errorf("%s not listed", name)
+		}
+	}
+	for _, name := range []string{"secret.txt", "etclink", "README.md"} {
+		if strings.Contains(body, name) {
+			t.Errorf("%s listed, although it cannot be opened", name)
+		}
+	}
+}
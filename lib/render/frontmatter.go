@@ -0,0 +1,70 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterFormat describes the fences and decoder of one front matter syntax.
+type frontMatterFormat struct {
+	fence  string
+	decode func(data []byte, meta *map[string]interface{}) error
+}
+
+// Supported front matter syntaxes
+var frontMatterFormats = []frontMatterFormat{
+	{"---", func(data []byte, meta *map[string]interface{}) error { return yaml.Unmarshal(data, meta) }},
+	{"+++", func(data []byte, meta *map[string]interface{}) error { return toml.Unmarshal(data, meta) }},
+}
+
+// splitFrontMatter separates a leading YAML (---) or TOML (+++) front matter block from the Markdown source. The
+// source is returned unchanged with nil metadata if there is no block, or if it cannot be parsed, since a leading ---
+// may just as well be a thematic break.
+func splitFrontMatter(source []byte) (map[string]interface{}, []byte) {
+	for _, format := range frontMatterFormats {
+		fence := []byte(format.fence)
+		first, rest := cutLine(source)
+		if !bytes.Equal(bytes.TrimRight(first, " \t\r"), fence) {
+			continue
+		}
+
+		var block []byte
+		for len(rest) > 0 {
+			var line []byte
+			line, rest = cutLine(rest)
+			trimmed := bytes.TrimRight(line, " \t\r")
+			if bytes.Equal(trimmed, fence) || format.fence == "---" && bytes.Equal(trimmed, []byte("...")) {
+				meta := make(map[string]interface{})
+				if err := format.decode(block, &meta); err != nil {
+					log.Printf("Ignoring front matter: %v", err)
+					return nil, source
+				}
+				return meta, rest
+			}
+			block = append(block, line...)
+			block = append(block, '\n')
+		}
+		return nil, source
+	}
+	return nil, source
+}
+
+// cutLine splits off the first line of b, without its line feed.
+func cutLine(b []byte) ([]byte, []byte) {
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		return b[:i], b[i+1:]
+	}
+	return b, nil
+}
+
+// metaTitle returns the title set in the front matter, if any.
+func metaTitle(meta map[string]interface{}) string {
+	if title, ok := meta["title"]; ok && title != nil {
+		return fmt.Sprint(title)
+	}
+	return ""
+}
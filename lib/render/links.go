@@ -0,0 +1,142 @@
+package render
+
+import (
+	"bytes"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// LocalLinks returns the paths of the local files that the Markdown source of fileName links to or embeds, relative to
+// the current directory. External URLs and in-page anchors are skipped.
+func LocalLinks(gm goldmark.Markdown, fileName string, source []byte) []string {
+	var links []string
+	dir := filepath.Dir(fileName)
+	doc := gm.Parser().Parse(text.NewReader(source))
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var dest []byte
+		switch node := n.(type) {
+		case *ast.Link:
+			dest = node.Destination
+		case *ast.Image:
+			dest = node.Destination
+		default:
+			return ast.WalkContinue, nil
+		}
+		if p, ok := localPath(dir, string(dest)); ok {
+			links = append(links, p)
+		}
+		return ast.WalkContinue, nil
+	})
+	return links
+}
+
+// localPath resolves a link destination against dir, reporting false if it does not point to a local file.
+func localPath(dir, dest string) (string, bool) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || strings.HasPrefix(dest, "//") {
+		return "", false
+	}
+	if strings.HasPrefix(u.Path, "/") {
+		// Absolute URLs are served relative to the current directory
+		return filepath.Clean(filepath.FromSlash(u.Path[1:])), true
+	}
+	return filepath.Join(dir, filepath.FromSlash(u.Path)), true
+}
+
+// Anchors defined by raw HTML
+var htmlAnchor = regexp.MustCompile(`(?i)\s(?:id|name)\s*=\s*["']([^"']+)["']`)
+
+// Reference is a link, image or autolink destination found in a document, with the line it is on.
+type Reference struct {
+	Destination string
+	Line        int
+	Image       bool
+}
+
+// ScanLinks returns the destinations referenced by the Markdown source, and the anchors it defines: the IDs of its
+// headings as generated by the parser, and the id and name attributes in its raw HTML. Lines are counted from the
+// start of the source, front matter included.
+func ScanLinks(gm goldmark.Markdown, source []byte) ([]Reference, map[string]bool) {
+	_, body := splitFrontMatter(source)
+	skipped := bytes.Count(source[:len(source)-len(body)], []byte("\n"))
+	lineAt := func(offset int) int {
+		return skipped + bytes.Count(body[:offset], []byte("\n")) + 1
+	}
+
+	var refs []Reference
+	anchors := make(map[string]bool)
+	addAnchors := func(html []byte) {
+		for _, m := range htmlAnchor.FindAllSubmatch(html, -1) {
+			anchors[string(m[1])] = true
+		}
+	}
+	doc := gm.Parser().Parse(text.NewReader(body))
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Heading:
+			if id, ok := node.AttributeString("id"); ok {
+				if b, ok := id.([]byte); ok {
+					anchors[string(b)] = true
+				}
+			}
+		case *ast.HTMLBlock:
+			lines := node.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				segment := lines.At(i)
+				addAnchors(segment.Value(body))
+			}
+		case *ast.RawHTML:
+			for i := 0; i < node.Segments.Len(); i++ {
+				segment := node.Segments.At(i)
+				addAnchors(segment.Value(body))
+			}
+		case *ast.Link:
+			refs = append(refs, Reference{Destination: string(node.Destination), Line: lineAt(offsetOf(node))})
+		case *ast.Image:
+			refs = append(refs, Reference{Destination: string(node.Destination), Line: lineAt(offsetOf(node)),
+				Image: true})
+		case *ast.AutoLink:
+			refs = append(refs, Reference{Destination: string(node.URL(body)), Line: lineAt(offsetOf(node))})
+		}
+		return ast.WalkContinue, nil
+	})
+	return refs, anchors
+}
+
+// offsetOf returns the position in the source of an inline node: that of its first text, or else the end of the text
+// before it, or else the start of the block containing it. Inline nodes do not record their own position.
+func offsetOf(n ast.Node) int {
+	offset := -1
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := c.(*ast.Text); ok && entering {
+			offset = t.Segment.Start
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	if offset >= 0 {
+		return offset
+	}
+	// Autolinks have no children, but usually follow some text
+	if t, ok := n.PreviousSibling().(*ast.Text); ok {
+		return t.Segment.Stop
+	}
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Type() == ast.TypeBlock && p.Lines().Len() > 0 {
+			return p.Lines().At(0).Start
+		}
+	}
+	return 0
+}
@@ -0,0 +1,399 @@
+package render
+
+import (
+	"bytes"
+	"html"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dienakakim/mds/lib/diagram"
+	"github.com/dienakakim/mds/lib/mathml"
+	"github.com/dienakakim/mds/lib/pdf"
+	. "github.com/dienakakim/mds/lib/structs"
+	"github.com/yuin/goldmark"
+	emojiast "github.com/yuin/goldmark-emoji/ast"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Sizes in points of body text, code, table cells and footnotes, and of headings by level
+const (
+	pdfTextSize  = 10.5
+	pdfCodeSize  = 9
+	pdfTableSize = 9.5
+	pdfNoteSize  = 8
+)
+
+var pdfHeadingSizes = [7]float64{0, 20, 16, 13.5, 12, 11, 10.5}
+
+// Indentation of list items and quotes
+const (
+	pdfListIndent  = 18
+	pdfQuoteIndent = 14
+)
+
+// Colours of PDF files, which always use the light theme
+var (
+	pdfText     = pdf.Color{R: 0.12, G: 0.16, B: 0.22}
+	pdfLink     = pdf.Color{R: 0.15, G: 0.39, B: 0.92}
+	pdfMuted    = pdf.Color{R: 0.42, G: 0.45, B: 0.5}
+	pdfCodeFill = pdf.Color{R: 0.95, G: 0.96, B: 0.96}
+)
+
+// pdfContext is the indentation and quote bars of the blocks being laid out.
+type pdfContext struct {
+	indent float64
+	bars   []float64
+}
+
+// pdfLayout lays out a parsed document as PDF pages.
+type pdfLayout struct {
+	doc    *pdf.Document
+	source []byte
+	dir    string
+	open   Resolver
+	notes  map[int]*east.Footnote
+	pages  map[string]int
+	marker []pdf.Span
+}
+
+// Resolver maps a slash-separated path to the file it may be read from, relative to the current directory, or refuses
+// it, as resolve.Root does.
+type Resolver func(urlPath string) (string, error)
+
+// BuildPDF lays out the Markdown source of config.FileName as a print-ready PDF file of A4 pages, always in the light
+// theme. A table of contents with page numbers comes first, headings become bookmarks, and footnotes go to the bottom
+// of the page that refers to them. Local images are embedded, while raw HTML is left out and diagrams are shown as
+// their source. Images are only read if resolve allows them.
+func BuildPDF(gm goldmark.Markdown, source []byte, config Config, resolve Resolver) ([]byte, error) {
+	meta, source := splitFrontMatter(source)
+	doc := gm.Parser().Parse(text.NewReader(source))
+	title := metaTitle(meta)
+	if title == "" {
+		_, title = filepath.Split(config.FileName)
+	}
+	notes := make(map[int]*east.Footnote)
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if fn, ok := n.(*east.Footnote); ok && entering {
+			notes[fn.Index] = fn
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// Page numbers in the table of contents are only known after a first pass
+	var layout *pdfLayout
+	var pages map[string]int
+	for pass := 0; pass < 2; pass++ {
+		layout = &pdfLayout{doc: pdf.New(title), source: source, dir: filepath.Dir(config.FileName), open: resolve,
+			notes: notes, pages: pages}
+		layout.document(doc)
+		pages = make(map[string]int)
+		for _, h := range headings(doc, source) {
+			pages[h.ID] = layout.doc.PageOf(h.ID)
+		}
+	}
+	var out bytes.Buffer
+	err := layout.doc.Write(&out)
+	return out.Bytes(), err
+}
+
+// document lays out doc, with the table of contents where a [TOC] paragraph asks for it, or else below the title.
+func (l *pdfLayout) document(doc ast.Node) {
+	placeholder := false
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		placeholder = placeholder || l.isTOC(n)
+	}
+	first := doc.FirstChild()
+	if h, ok := first.(*ast.Heading); ok && h.Level == 1 && !placeholder {
+		l.block(first, pdfContext{})
+		first = first.NextSibling()
+	}
+	if !placeholder {
+		l.toc(doc)
+	}
+	for n := first; n != nil; n = n.NextSibling() {
+		l.block(n, pdfContext{})
+	}
+}
+
+// isTOC reports whether n is a [TOC] paragraph.
+func (l *pdfLayout) isTOC(n ast.Node) bool {
+	_, ok := n.(*ast.Paragraph)
+	return ok && strings.TrimSpace(string(n.Text(l.source))) == "[TOC]"
+}
+
+// toc lays out the table of contents of doc, if it has more than one heading.
+func (l *pdfLayout) toc(doc ast.Node) {
+	entries := headings(doc, l.source)
+	if len(entries) < 2 {
+		return
+	}
+	top := entries[0].Level
+	for _, e := range entries {
+		if e.Level < top {
+			top = e.Level
+		}
+	}
+	l.doc.Paragraph([]pdf.Span{{Text: "Contents", Size: pdfHeadingSizes[3], Bold: true, Color: pdfText}},
+		pdf.Block{Before: 12, After: 6})
+	for _, e := range entries {
+		// The first pass leaves room for page numbers, so that both lay out alike
+		page := " "
+		if n := l.pages[e.ID]; n > 0 {
+			page = strconv.Itoa(n)
+		}
+		l.doc.Paragraph([]pdf.Span{{Text: e.Title, Size: pdfTextSize, Color: pdfText, Link: "#" + e.ID}},
+			pdf.Block{Indent: float64(e.Level-top) * pdfListIndent, After: 2, Right: page})
+	}
+	l.doc.Rule()
+}
+
+// blocks lays out the children of n.
+func (l *pdfLayout) blocks(n ast.Node, c pdfContext) {
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		l.block(child, c)
+	}
+}
+
+// block lays out a block node.
+func (l *pdfLayout) block(n ast.Node, c pdfContext) {
+	switch node := n.(type) {
+	case *ast.Heading:
+		size := pdfHeadingSizes[node.Level]
+		var id string
+		if attr, ok := node.AttributeString("id"); ok {
+			if b, ok := attr.([]byte); ok {
+				id = string(b)
+			}
+		}
+		l.paragraph(l.inlines(node, pdf.Span{Size: size, Bold: true, Color: pdfText}), c,
+			pdf.Block{Before: size, After: size * 0.4, Keep: 3 * pdfTextSize * 1.35, Anchor: id})
+		l.doc.Bookmark(html.UnescapeString(string(node.Text(l.source))), node.Level, id)
+	case *ast.Paragraph, *ast.TextBlock:
+		if l.isTOC(n) {
+			l.toc(n.OwnerDocument())
+			return
+		}
+		if img, ok := n.FirstChild().(*ast.Image); ok && n.ChildCount() == 1 && l.image(img, c) {
+			return
+		}
+		after := 8.0
+		if _, ok := n.(*ast.TextBlock); ok {
+			after = 3
+		}
+		l.paragraph(l.inlines(n, pdf.Span{Size: pdfTextSize, Color: pdfText}), c, pdf.Block{After: after})
+	case *ast.List:
+		number := node.Start
+		for item := node.FirstChild(); item != nil; item = item.NextSibling() {
+			marker := "•"
+			if node.IsOrdered() {
+				marker = strconv.Itoa(number) + "."
+				number++
+			}
+			l.marker = []pdf.Span{{Text: marker, Size: pdfTextSize, Color: pdfText}}
+			l.blocks(item, pdfContext{indent: c.indent + pdfListIndent, bars: c.bars})
+			l.marker = nil
+		}
+	case *ast.Blockquote:
+		bars := append(append([]float64(nil), c.bars...), c.indent+3)
+		l.blocks(n, pdfContext{indent: c.indent + pdfQuoteIndent, bars: bars})
+	case *ast.FencedCodeBlock, *ast.CodeBlock, *diagram.Diagram:
+		code := strings.TrimRight(linesText(n, l.source), "\n")
+		l.paragraph([]pdf.Span{{Text: code, Size: pdfCodeSize, Mono: true, Color: pdfText}}, c,
+			pdf.Block{Indent: 6, Before: 4, After: 10, Leading: 1.45, Fill: &pdfCodeFill, Pre: true})
+	case *mathml.MathBlock:
+		tex := strings.TrimSpace(linesText(n, l.source))
+		l.paragraph([]pdf.Span{{Text: tex, Size: pdfTextSize, Italic: true, Color: pdfText}}, c,
+			pdf.Block{Indent: 2 * pdfListIndent, Before: 4, After: 8, Pre: true})
+	case *ast.ThematicBreak:
+		l.doc.Rule()
+	case *east.Table:
+		l.table(node, c)
+	case *east.DefinitionTerm:
+		l.paragraph(l.inlines(node, pdf.Span{Size: pdfTextSize, Bold: true, Color: pdfText}), c, pdf.Block{After: 3})
+	case *east.DefinitionDescription:
+		l.blocks(n, pdfContext{indent: c.indent + pdfListIndent, bars: c.bars})
+	case *ast.HTMLBlock, *east.FootnoteList:
+		// Raw HTML cannot be laid out, and footnotes go with their references
+	default:
+		l.blocks(n, c)
+	}
+}
+
+// paragraph lays out spans in context c, with the marker of the list item it starts, if any.
+func (l *pdfLayout) paragraph(spans []pdf.Span, c pdfContext, b pdf.Block) {
+	b.Indent += c.indent
+	b.Bars = c.bars
+	b.Marker, l.marker = l.marker, nil
+	l.doc.Paragraph(spans, b)
+}
+
+// image embeds a local image standing alone in a paragraph, reporting false if it cannot be read. Images that would
+// not be served, such as those outside the current directory or behind symlinks leading out of it, are never read.
+func (l *pdfLayout) image(n *ast.Image, c pdfContext) bool {
+	p, ok := localPath(l.dir, string(n.Destination))
+	if !ok || filepath.IsAbs(p) {
+		return false
+	}
+	fileName, err := l.open(filepath.ToSlash(p))
+	if err != nil {
+		return false
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return false
+	}
+	l.marker = nil
+	l.doc.Image(img, c.indent)
+	return true
+}
+
+// table lays out a table, with its header shaded.
+func (l *pdfLayout) table(n *east.Table, c pdfContext) {
+	var rows [][][]pdf.Span
+	header := 0
+	for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+		_, isHeader := row.(*east.TableHeader)
+		if isHeader {
+			header++
+		}
+		var cells [][]pdf.Span
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, l.inlines(cell, pdf.Span{Size: pdfTableSize, Bold: isHeader, Color: pdfText}))
+		}
+		rows = append(rows, cells)
+	}
+	aligns := make([]pdf.Align, len(n.Alignments))
+	for i, a := range n.Alignments {
+		switch a {
+		case east.AlignCenter:
+			aligns[i] = pdf.AlignCenter
+		case east.AlignRight:
+			aligns[i] = pdf.AlignRight
+		}
+	}
+	l.doc.Table(rows, header, aligns, c.indent)
+}
+
+// inlines converts the inline children of n to spans, starting from the given style.
+func (l *pdfLayout) inlines(n ast.Node, style pdf.Span) []pdf.Span {
+	var spans []pdf.Span
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		s := style
+		switch node := child.(type) {
+		case *ast.Text:
+			s.Text = string(node.Segment.Value(l.source))
+			if node.HardLineBreak() {
+				s.Text += "\n"
+			} else if node.SoftLineBreak() {
+				s.Text += " "
+			}
+			spans = append(spans, s)
+		case *ast.String:
+			// Typographer substitutions are HTML entities
+			s.Text = html.UnescapeString(string(node.Value))
+			spans = append(spans, s)
+		case *ast.CodeSpan:
+			s.Mono, s.Size = true, style.Size*0.92
+			spans = append(spans, l.inlines(node, s)...)
+		case *ast.Emphasis:
+			if node.Level >= 2 {
+				s.Bold = true
+			} else {
+				s.Italic = true
+			}
+			spans = append(spans, l.inlines(node, s)...)
+		case *ast.Link:
+			s.Color, s.Link = pdfLink, pdfTarget(string(node.Destination))
+			spans = append(spans, l.inlines(node, s)...)
+		case *ast.AutoLink:
+			s.Color, s.Link, s.Text = pdfLink, pdfTarget(string(node.URL(l.source))), string(node.Label(l.source))
+			spans = append(spans, s)
+		case *ast.Image:
+			// Only images standing alone are drawn
+			s.Color, s.Italic, s.Text = pdfMuted, true, string(node.Text(l.source))
+			spans = append(spans, s)
+		case *ast.RawHTML:
+			if raw := strings.ToLower(string(node.Segments.Value(l.source))); strings.HasPrefix(raw, "<br") {
+				s.Text = "\n"
+				spans = append(spans, s)
+			}
+		case *east.Strikethrough:
+			s.Strike = true
+			spans = append(spans, l.inlines(node, s)...)
+		case *east.TaskCheckBox:
+			s.Mono, s.Text = true, "[ ] "
+			if node.IsChecked {
+				s.Text = "[x] "
+			}
+			spans = append(spans, s)
+		case *east.FootnoteLink:
+			spans = append(spans, pdf.Span{Text: strconv.Itoa(node.Index), Size: style.Size * 0.65,
+				Rise: style.Size * 0.4, Color: pdfLink, Note: l.note(node.Index)})
+		case *east.FootnoteBacklink:
+		case *mathml.InlineMath:
+			s.Italic, s.Text = true, string(node.TeX)
+			spans = append(spans, s)
+		case *emojiast.Emoji:
+			// The standard fonts have no emoji
+			s.Text = ":" + string(node.ShortName) + ":"
+			spans = append(spans, s)
+		default:
+			spans = append(spans, l.inlines(child, style)...)
+		}
+	}
+	return spans
+}
+
+// note returns the text of footnote index, preceded by its number.
+func (l *pdfLayout) note(index int) []pdf.Span {
+	fn, ok := l.notes[index]
+	if !ok {
+		return nil
+	}
+	spans := []pdf.Span{{Text: strconv.Itoa(index), Size: pdfNoteSize * 0.75, Rise: pdfNoteSize * 0.35, Color: pdfText}}
+	for p := fn.FirstChild(); p != nil; p = p.NextSibling() {
+		spans = append(spans, pdf.Span{Text: " ", Size: pdfNoteSize})
+		spans = append(spans, l.inlines(p, pdf.Span{Size: pdfNoteSize, Color: pdfText})...)
+	}
+	return spans
+}
+
+// pdfTarget returns where a link destination leads in a PDF file: to an anchor in the document, or to an absolute
+// URL. Links to other local files lead nowhere in a file of its own.
+func pdfTarget(dest string) string {
+	u, err := url.Parse(dest)
+	switch {
+	case err != nil:
+		return ""
+	case u.Scheme != "":
+		return dest
+	case u.Path == "" && u.Fragment != "":
+		return "#" + u.Fragment
+	}
+	return ""
+}
+
+// linesText returns the source lines of a block.
+func linesText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		b.Write(segment.Value(source))
+	}
+	return b.String()
+}
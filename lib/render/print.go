@@ -0,0 +1,60 @@
+package render
+
+import (
+	"html/template"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/dienakakim/mds/lib/structs"
+	"github.com/yuin/goldmark"
+)
+
+// BuildPrint converts the Markdown source of config.FileName into a page meant for printing: without controls or
+// scripts, and with the table of contents at the top unless a [TOC] paragraph already placed it.
+func BuildPrint(gm goldmark.Markdown, source []byte, config Config) RenderedHTML {
+	config.LiveReload, config.Search = false, false
+	rendered := Build(gm, source, config, nil)
+	rendered.Print = true
+	if strings.Contains(string(rendered.Body), string(rendered.TOC)) {
+		rendered.TOC = ""
+	}
+	return rendered
+}
+
+// RenderPrint writes config.FileName as a page meant for printing, or as a PDF file if asPDF is set, with the images
+// that resolve allows. Directories are exported through their index file.
+func RenderPrint(w http.ResponseWriter, gm goldmark.Markdown, templ *template.Template, config Config, asPDF bool,
+	resolve Resolver) {
+	if info, err := os.Stat(config.FileName); err == nil && info.IsDir() {
+		if index, ok := indexFile(config.FileName, resolve); ok {
+			config.FileName = index
+		}
+	}
+	content, err := ioutil.ReadFile(config.FileName)
+	if err != nil || !strings.HasSuffix(config.FileName, ".md") {
+		notFound(w, config.FileName)
+		return
+	}
+	if !asPDF {
+		rendered := BuildPrint(gm, content, config)
+		secure(w, &rendered, config)
+		templ.Execute(w, rendered)
+		return
+	}
+
+	doc, err := BuildPDF(gm, content, config, resolve)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	name := strings.TrimSuffix(filepath.Base(config.FileName), ".md") + ".pdf"
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": name}))
+	w.Write(doc)
+}
@@ -7,41 +7,143 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 
+	"github.com/dienakakim/mds/lib/cache"
+	"github.com/dienakakim/mds/lib/nav"
+	"github.com/dienakakim/mds/lib/sanitize"
+	"github.com/dienakakim/mds/lib/search"
 	. "github.com/dienakakim/mds/lib/structs"
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
 )
 
 // Default text to display when goldmark fails to render markdown
 const errorText = "Failed to parse markdown"
 
-// render uses the given Goldmark instance to render the HTML.
-func Render(w http.ResponseWriter, r *http.Request, gm goldmark.Markdown, templ *template.Template, config Config) {
-	content, err := ioutil.ReadFile(config.FileName)
+// Build converts the Markdown source of config.FileName into the template struct. Front matter is stripped from the
+// source and exposed as metadata. If rewrite is not nil, it is applied to the parsed document before rendering.
+func Build(gm goldmark.Markdown, source []byte, config Config, rewrite func(doc ast.Node)) RenderedHTML {
+	meta, source := splitFrontMatter(source)
+	doc := gm.Parser().Parse(text.NewReader(source))
+	if rewrite != nil {
+		rewrite(doc)
+	}
+	var html bytes.Buffer
+	if err := gm.Renderer().Render(&html, source, doc); err != nil {
+		log.Println(errorText)
+	}
+	body := html.Bytes()
+	if config.Safe {
+		body = sanitize.HTML(body)
+	}
+	toc := tableOfContents(doc, source)
+	body = insertTOC(body, toc)
+	_, fileName := filepath.Split(config.FileName)
+	title := metaTitle(meta)
+	if title == "" {
+		title = fileName
+	}
+	return RenderedHTML{Body: template.HTML(body), Style: config.Style, FileName: fileName,
+		Path: filepath.ToSlash(config.FileName), LiveReload: config.LiveReload, Theme: config.Theme, Dark: config.DarkMode,
+		TOC: toc, Title: title, Meta: meta, Search: config.Search, Control: config.Control,
+		Mermaid: config.Mermaid && bytes.Contains(body, []byte(`<pre class="mermaid">`)), Served: config.Served}
+}
+
+// render uses the given Goldmark instance to render the HTML. Rendered pages are looked up in and added to pages, and
+// index provides the targets of wiki links and the backlinks of each page. Directories only show what resolve allows.
+func Render(w http.ResponseWriter, r *http.Request, gm goldmark.Markdown, templ *template.Template, config Config,
+	pages *cache.Cache, index *search.Index, resolve Resolver) {
+	if info, err := os.Stat(config.FileName); err == nil && info.IsDir() {
+		// Relative links only resolve correctly below a trailing slash
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			target := url.URL{Path: r.URL.Path + "/", RawQuery: r.URL.RawQuery}
+			http.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+			return
+		}
+		if index, ok := indexFile(config.FileName, resolve); ok {
+			config.FileName = index
+		} else {
+			renderDirectory(w, r, templ, config, resolve)
+			return
+		}
+	}
+	if !strings.HasSuffix(config.FileName, ".md") {
+		// Arbitrary file
+		serveFile(w, r, config.FileName, config.Safe)
+		return
+	}
+
+	// Markdown file, only converted again if it changed since it was cached
+	info, err := os.Stat(config.FileName)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		err := fmt.Sprintf("404: \"%s\" cannot be opened", config.FileName)
-		fmt.Fprintln(w, err)
-		log.Println(err)
+		notFound(w, config.FileName)
 		return
 	}
-	if strings.HasSuffix(config.FileName, ".md") {
-		// Markdown file
-		var html bytes.Buffer
-		if err := gm.Convert(content, &html); err != nil {
-			log.Println(errorText)
+	// Links between documents change as other documents do
+	index.Update()
+	key := cache.Key{Path: config.FileName, ModTime: info.ModTime(), Size: info.Size(),
+		Options: fmt.Sprintf("theme=%s math=%t live=%t index=%d", config.Theme, config.Math, config.LiveReload,
+			index.Version())}
+	rendered, ok := pages.Get(key)
+	if !ok {
+		content, err := ioutil.ReadFile(config.FileName)
+		if err != nil {
+			notFound(w, config.FileName)
+			return
 		}
-		_, fileName := filepath.Split(config.FileName)
-		rendered := RenderedHTML{Body: template.HTML(html.String()), Style: template.CSS(string(config.StyleBytes)), FileName: fileName}
-		templ.Execute(w, rendered)
-	} else {
-		// Arbitrary file
-		w.WriteHeader(http.StatusOK)
-		w.Header().Set("Content-Type", "application/octet-stream")
-		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
-		w.Write(content)
+		rendered = Build(gm, content, config, nil)
+		rendered.Backlinks = index.Backlinks(filepath.ToSlash(config.FileName))
+		if pages := index.Pages(); config.Nav && len(pages) > 1 {
+			rendered.Nav, rendered.Prev, rendered.Next = nav.Locate(nav.Tree(".", gm, pages),
+				filepath.ToSlash(config.FileName))
+		}
+		pages.Add(key, rendered)
+	}
+	rendered.Style = config.Style
+	secure(w, &rendered, config)
+	templ.Execute(w, rendered)
+}
+
+// secure lets only the scripts of the page template run in safe mode, by giving them a nonce required by the
+// Content-Security-Policy header.
+func secure(w http.ResponseWriter, rendered *RenderedHTML, config Config) {
+	if config.Safe {
+		rendered.Nonce = sanitize.Nonce()
+		w.Header().Set("Content-Security-Policy", sanitize.ContentSecurityPolicy(rendered.Nonce))
+	}
+}
+
+// serveFile streams an arbitrary file. http.ServeContent picks the MIME type from the extension or by sniffing, and
+// handles Range requests and conditional GETs against Last-Modified and the ETag set here. In safe mode, the file is
+// sandboxed, as it may be HTML or SVG with scripts.
+func serveFile(w http.ResponseWriter, r *http.Request, fileName string, safe bool) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		notFound(w, fileName)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		notFound(w, fileName)
+		return
 	}
+	if safe {
+		w.Header().Set("Content-Security-Policy", sanitize.FilePolicy)
+	}
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// notFound reports that fileName cannot be opened.
+func notFound(w http.ResponseWriter, fileName string) {
+	w.WriteHeader(http.StatusNotFound)
+	err := fmt.Sprintf("404: \"%s\" cannot be opened", fileName)
+	fmt.Fprintln(w, err)
+	log.Println(err)
 }
@@ -0,0 +1,47 @@
+package render
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dienakakim/mds/lib/sanitize"
+	. "github.com/dienakakim/mds/lib/structs"
+)
+
+func TestRenderSandboxesFilesInSafeMode(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"evil.html": "<!DOCTYPE html><script>alert(sessionStorage.getItem('mds-token'))</script>",
+		"evil.svg":  `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`,
+		"evil":      "<html><script>alert(1)</script></html>",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name := range files {
+		for _, safe := range []bool{true, false} {
+			w := httptest.NewRecorder()
+			config := Config{FileName: filepath.Join(dir, name), Safe: safe}
+			Render(w, httptest.NewRequest(http.MethodGet, "/"+name, nil), nil, nil, config, nil, nil, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: status %d", name, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), "<script>") {
+				t.Errorf("%s: content not served as it is", name)
+			}
+			policy := w.Header().Get("Content-Security-Policy")
+			switch {
+			case safe && policy != sanitize.FilePolicy:
+				t.Errorf("%s: Content-Security-Policy %q in safe mode, want %q", name, policy, sanitize.FilePolicy)
+			case !safe && policy != "":
+				t.Errorf("%s: Content-Security-Policy %q outside safe mode, want none", name, policy)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package render
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/dienakakim/mds/lib/search"
+	. "github.com/dienakakim/mds/lib/structs"
+	"github.com/dienakakim/mds/lib/wikilink"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// resultsTemplate renders the body of a search results page.
+var resultsTemplate = template.Must(template.New("results").Parse(`<h1>Search</h1>
+{{- if .Query}}
+<p class="opacity-75">{{len .Results}} result{{if ne (len .Results) 1}}s{{end}} for “{{.Query}}”</p>
+<ul class="list-none">
+{{- range .Results}}
+    <li class="py-2">
+        <a class="font-bold" href="/{{.Path}}">{{.Title}}</a> <span class="opacity-75 text-sm">{{.Path}}</span>
+        <p class="text-sm">{{.Snippet}}</p>
+    </li>
+{{- end}}
+</ul>
+{{- end}}
+`))
+
+// Extract returns the content of the Markdown source of fileName to index: its text without markup or front matter,
+// its title, which is the one set in the front matter or else the first heading, and the Markdown files it links to.
+func Extract(gm goldmark.Markdown, fileName string, source []byte) search.Content {
+	meta, source := splitFrontMatter(source)
+	title := metaTitle(meta)
+	dir := filepath.Dir(fileName)
+	doc := gm.Parser().Parse(text.NewReader(source))
+	var content bytes.Buffer
+	var links, wikiLinks []string
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			if n.Type() == ast.TypeBlock {
+				content.WriteByte('\n')
+			}
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Heading:
+			if title == "" {
+				title = string(node.Text(source))
+			}
+		case *ast.Text:
+			content.Write(node.Segment.Value(source))
+			if node.SoftLineBreak() || node.HardLineBreak() {
+				content.WriteByte(' ')
+			}
+		case *ast.String:
+			content.Write(node.Value)
+		case *ast.AutoLink:
+			content.Write(node.URL(source))
+		case *ast.Link:
+			if target, ok := node.AttributeString(wikilink.TargetAttribute); ok {
+				if b, ok := target.([]byte); ok {
+					wikiLinks = append(wikiLinks, strings.SplitN(string(b), "#", 2)[0])
+				}
+			} else if p, ok := localPath(dir, string(node.Destination)); ok && strings.HasSuffix(p, ".md") {
+				links = append(links, filepath.ToSlash(p))
+			}
+		case *ast.CodeBlock, *ast.FencedCodeBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				segment := lines.At(i)
+				content.Write(segment.Value(source))
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return search.Content{Title: title, Text: strings.TrimSpace(content.String()), Links: links, WikiLinks: wikiLinks}
+}
+
+// RenderSearch writes a themed page listing the search results for query.
+func RenderSearch(w http.ResponseWriter, r *http.Request, templ *template.Template, config Config, query string,
+	results []search.Result) {
+	var body bytes.Buffer
+	page := struct {
+		Query   string
+		Results []search.Result
+	}{query, results}
+	if err := resultsTemplate.Execute(&body, page); err != nil {
+		log.Println(err)
+	}
+	title := "Search"
+	if query != "" {
+		title = query + " - Search"
+	}
+	rendered := RenderedHTML{Body: template.HTML(body.String()), Style: config.Style, FileName: title,
+		Title: title, Theme: config.Theme, Dark: config.DarkMode, Search: config.Search, Query: query,
+		Control: config.Control, Served: config.Served}
+	templ.Execute(w, rendered)
+}
@@ -0,0 +1,297 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"reflect"
+	"text/template/parse"
+
+	. "github.com/dienakakim/mds/lib/structs"
+)
+
+// Maximum depth of nested values filled in when validating a template
+const sampleDepth = 4
+
+// ValidateTemplate checks that templ only refers to fields of RenderedHTML. Every branch is checked, whichever values
+// would lead to it, and the templates it invokes are checked against the values passed to them. The template is then
+// executed against a sample in which every field is set, which also reports markup it cannot be escaped in.
+func ValidateTemplate(templ *template.Template) error {
+	c := checker{templ: templ, checked: make(map[string]bool)}
+	if err := c.template(templ.Name(), reflect.TypeOf(RenderedHTML{})); err != nil {
+		return fmt.Errorf("template does not match the fields of RenderedHTML: %v", err)
+	}
+	var sample RenderedHTML
+	fill(reflect.ValueOf(&sample).Elem(), sampleDepth)
+	if err := templ.Execute(ioutil.Discard, sample); err != nil {
+		return fmt.Errorf("template does not match the fields of RenderedHTML: %v", err)
+	}
+	return nil
+}
+
+// checker follows the type of dot and of variables through the parse trees of a template. A nil type stands for a
+// value whose type is only known when the template runs, such as the result of a function, and is not checked.
+type checker struct {
+	templ   *template.Template
+	tree    *parse.Tree
+	checked map[string]bool
+}
+
+// template checks the template called name, run with a value of type dot.
+func (c *checker) template(name string, dot reflect.Type) error {
+	key := fmt.Sprintf("%s %v", name, dot)
+	if c.checked[key] {
+		return nil
+	}
+	c.checked[key] = true
+	t := c.templ.Lookup(name)
+	if t == nil || t.Tree == nil {
+		return fmt.Errorf("no such template %q", name)
+	}
+	caller := c.tree
+	c.tree = t.Tree
+	defer func() { c.tree = caller }()
+	return c.list(t.Tree.Root, dot, map[string]reflect.Type{"$": dot})
+}
+
+// list checks the nodes of a block, in which variables declared inside stay until the end of the block.
+func (c *checker) list(list *parse.ListNode, dot reflect.Type, vars map[string]reflect.Type) error {
+	if list == nil {
+		return nil
+	}
+	scope := make(map[string]reflect.Type, len(vars))
+	for name, t := range vars {
+		scope[name] = t
+	}
+	for _, node := range list.Nodes {
+		if err := c.node(node, dot, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// node checks a single node and the blocks inside it.
+func (c *checker) node(node parse.Node, dot reflect.Type, vars map[string]reflect.Type) error {
+	switch n := node.(type) {
+	case *parse.ActionNode:
+		_, err := c.pipe(n.Pipe, dot, vars)
+		return err
+	case *parse.IfNode:
+		return c.branch(&n.BranchNode, dot, vars, func(t reflect.Type) reflect.Type { return dot })
+	case *parse.WithNode:
+		return c.branch(&n.BranchNode, dot, vars, func(t reflect.Type) reflect.Type { return t })
+	case *parse.RangeNode:
+		return c.branch(&n.BranchNode, dot, vars, elem)
+	case *parse.TemplateNode:
+		var t reflect.Type
+		if n.Pipe != nil {
+			var err error
+			if t, err = c.pipe(n.Pipe, dot, vars); err != nil {
+				return err
+			}
+		}
+		if t == nil {
+			// Only known when the template runs
+			if c.templ.Lookup(n.Name) == nil {
+				return fmt.Errorf("no such template %q", n.Name)
+			}
+			return nil
+		}
+		return c.template(n.Name, t)
+	case *parse.ListNode:
+		return c.list(n, dot, vars)
+	}
+	return nil
+}
+
+// branch checks an if, with or range node. Its body runs with dot set to body applied to the type of the pipeline,
+// and its else branch with dot unchanged.
+func (c *checker) branch(n *parse.BranchNode, dot reflect.Type, vars map[string]reflect.Type,
+	body func(t reflect.Type) reflect.Type) error {
+	scope := make(map[string]reflect.Type, len(vars))
+	for name, t := range vars {
+		scope[name] = t
+	}
+	t, err := c.pipe(n.Pipe, dot, scope)
+	if err != nil {
+		return err
+	}
+	if n.NodeType == parse.NodeRange && len(n.Pipe.Decl) > 0 {
+		// Range declares the element, or the key and the element
+		scope[n.Pipe.Decl[0].Ident[0]] = key(t)
+		scope[n.Pipe.Decl[len(n.Pipe.Decl)-1].Ident[0]] = elem(t)
+	}
+	if err := c.list(n.List, body(t), scope); err != nil {
+		return err
+	}
+	return c.list(n.ElseList, dot, scope)
+}
+
+// pipe checks a pipeline, declaring its variables, and returns the type of its value.
+func (c *checker) pipe(pipe *parse.PipeNode, dot reflect.Type, vars map[string]reflect.Type) (reflect.Type, error) {
+	var t reflect.Type
+	for _, cmd := range pipe.Cmds {
+		var err error
+		if t, err = c.command(cmd, dot, vars); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range pipe.Decl {
+		vars[v.Ident[0]] = t
+	}
+	return t, nil
+}
+
+// command checks the arguments of a command and returns the type of its value.
+func (c *checker) command(cmd *parse.CommandNode, dot reflect.Type, vars map[string]reflect.Type) (reflect.Type,
+	error) {
+	var t reflect.Type
+	for _, arg := range cmd.Args {
+		var err error
+		if t, err = c.arg(arg, dot, vars); err != nil {
+			return nil, err
+		}
+	}
+	if len(cmd.Args) != 1 {
+		// Function calls
+		return nil, nil
+	}
+	return t, nil
+}
+
+// arg returns the type of a single argument, checking the fields it refers to.
+func (c *checker) arg(arg parse.Node, dot reflect.Type, vars map[string]reflect.Type) (reflect.Type, error) {
+	switch n := arg.(type) {
+	case *parse.DotNode:
+		return dot, nil
+	case *parse.FieldNode:
+		return c.fields(n, dot, n.Ident)
+	case *parse.VariableNode:
+		return c.fields(n, vars[n.Ident[0]], n.Ident[1:])
+	case *parse.ChainNode:
+		t, err := c.arg(n.Node, dot, vars)
+		if err != nil {
+			return nil, err
+		}
+		return c.fields(n, t, n.Field)
+	case *parse.PipeNode:
+		return c.pipe(n, dot, vars)
+	}
+	return nil, nil
+}
+
+// fields follows a chain of field names from a value of type t, and returns the type of the last one.
+func (c *checker) fields(node parse.Node, t reflect.Type, names []string) (reflect.Type, error) {
+	for _, name := range names {
+		if t == nil {
+			return nil, nil
+		}
+		method, ok := t.MethodByName(name)
+		if !ok && t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface {
+			method, ok = reflect.PtrTo(t).MethodByName(name)
+		}
+		if ok {
+			if method.Type.NumOut() == 0 {
+				return nil, nil
+			}
+			t = method.Type.Out(0)
+			continue
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch {
+		case t.Kind() == reflect.Interface:
+			return nil, nil
+		case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+			t = t.Elem()
+		case t.Kind() == reflect.Struct:
+			field, ok := t.FieldByName(name)
+			if !ok || field.PkgPath != "" {
+				location, _ := c.tree.ErrorContext(node)
+				return nil, fmt.Errorf("%s: can't evaluate field %s in type %v", location, name, t)
+			}
+			t = field.Type
+		default:
+			location, _ := c.tree.ErrorContext(node)
+			return nil, fmt.Errorf("%s: can't evaluate field %s in type %v", location, name, t)
+		}
+	}
+	return t, nil
+}
+
+// elem returns the type of the elements range visits in a value of type t.
+func elem(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return t.Elem()
+	}
+	return nil
+}
+
+// key returns the type of the keys range visits in a value of type t.
+func key(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return reflect.TypeOf(0)
+	case reflect.Map:
+		return t.Key()
+	}
+	return nil
+}
+
+// fill sets v to a non-zero value, recursing into structs, slices, maps and pointers up to the given depth.
+func fill(v reflect.Value, depth int) {
+	if depth == 0 || !v.CanSet() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.String:
+		v.SetString("sample")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fill(v.Field(i), depth)
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte("sample"))
+			return
+		}
+		s := reflect.MakeSlice(v.Type(), 1, 1)
+		fill(s.Index(0), depth-1)
+		v.Set(s)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		key := reflect.New(v.Type().Key()).Elem()
+		fill(key, depth-1)
+		elem := reflect.New(v.Type().Elem()).Elem()
+		fill(elem, depth-1)
+		m.SetMapIndex(key, elem)
+		v.Set(m)
+	case reflect.Ptr:
+		p := reflect.New(v.Type().Elem())
+		fill(p.Elem(), depth-1)
+		v.Set(p)
+	}
+}
@@ -0,0 +1,50 @@
+package render
+
+import (
+	"html/template"
+	"testing"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	nav := `{{define "nav"}}{{range .}}{{.Title}}{{template "nav" .Children}}{{end}}{{end}}`
+	tests := []struct {
+		text  string
+		valid bool
+	}{
+		{"{{.Body}}", true},
+		{"{{.Bodyy}}", false},
+		{"{{.Title.Text}}", false},
+		{"{{.Meta.title}}", true},
+
+		// Branches a page only takes for some values
+		{"{{if not .Print}}{{.Bodyy}}{{end}}", false},
+		{"{{if .Print}}{{.Body}}{{else}}{{.Bodyy}}{{end}}", false},
+		{"{{if .Print}}{{else if not .Dark}}{{.Bodyy}}{{end}}", false},
+		{"{{if and .Served (not .Printt)}}{{end}}", false},
+		{"{{if and .Served (not .Print)}}{{.Path}}{{end}}", true},
+
+		// Dot and variables changed by with and range
+		{"{{with .Prev}}{{.Path}} {{.Title}}{{end}}", true},
+		{"{{with .Prev}}{{.Body}}{{end}}", false},
+		{"{{with .Prev}}{{.Path}}{{else}}{{.Pathh}}{{end}}", false},
+		{"{{range .Backlinks}}{{.Path}} {{$.Body}}{{end}}", true},
+		{"{{range .Backlinks}}{{.Body}}{{end}}", false},
+		{"{{range $i, $link := .Backlinks}}{{$i}} {{$link.Titel}}{{end}}", false},
+		{"{{$prev := .Prev}}{{if not .Print}}{{$prev.Titel}}{{end}}", false},
+		{"{{(index .Backlinks 0).Path}}", true},
+
+		// Templates invoked with other values
+		{nav + `{{template "nav" .Nav}}`, true},
+		{`{{define "nav"}}{{range .}}{{.Titel}}{{end}}{{end}}{{if not .Nav}}{{template "nav" .Nav}}{{end}}`, false},
+		{`{{define "page"}}{{.Body}}{{end}}{{template "page" .Prev}}`, false},
+	}
+	for _, tt := range tests {
+		templ := template.Must(template.New("test").Parse(tt.text))
+		err := ValidateTemplate(templ)
+		if tt.valid && err != nil {
+			t.Errorf("%s: %v", tt.text, err)
+		} else if !tt.valid && err == nil {
+			t.Errorf("%s: accepted", tt.text)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package render
+
+import (
+	"bytes"
+	"html"
+	"html/template"
+	"log"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// Paragraph marking where the table of contents goes inside a document
+const tocPlaceholder = "<p>[TOC]</p>"
+
+// tocTemplate renders nested headings as nested lists.
+var tocTemplate = template.Must(template.New("toc").Parse(
+	`{{define "entries"}}<ul>{{range .}}<li><a href="#{{.ID}}">{{.Title}}</a>{{if .Children}}{{template "entries" .Children}}{{end}}</li>{{end}}</ul>{{end}}` +
+		`<nav class="toc">{{template "entries" .}}</nav>`))
+
+// tocEntry is a heading in the table of contents, with the headings below it nested inside.
+type tocEntry struct {
+	Level    int
+	ID       string
+	Title    string
+	Children []*tocEntry
+}
+
+// headings returns the headings in doc in order, with the IDs generated by the parser.
+func headings(doc ast.Node, source []byte) []*tocEntry {
+	var entries []*tocEntry
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		entry := &tocEntry{Level: heading.Level, Title: html.UnescapeString(string(heading.Text(source)))}
+		if id, ok := heading.AttributeString("id"); ok {
+			if b, ok := id.([]byte); ok {
+				entry.ID = string(b)
+			}
+		}
+		entries = append(entries, entry)
+		return ast.WalkSkipChildren, nil
+	})
+	return entries
+}
+
+// tableOfContents builds the outline of the headings in doc. It returns an empty string if the document has no
+// headings.
+func tableOfContents(doc ast.Node, source []byte) template.HTML {
+	root := &tocEntry{}
+	stack := []*tocEntry{root}
+	for _, entry := range headings(doc, source) {
+		// Nest below the closest preceding heading of a higher level
+		for len(stack) > 1 && stack[len(stack)-1].Level >= entry.Level {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1]
+		parent.Children = append(parent.Children, entry)
+		stack = append(stack, entry)
+	}
+	if len(root.Children) == 0 {
+		return ""
+	}
+
+	var toc bytes.Buffer
+	if err := tocTemplate.Execute(&toc, root.Children); err != nil {
+		log.Println(err)
+		return ""
+	}
+	return template.HTML(toc.String())
+}
+
+// insertTOC replaces every [TOC] paragraph in the rendered body with the table of contents.
+func insertTOC(body []byte, toc template.HTML) []byte {
+	return bytes.Replace(body, []byte(tocPlaceholder), []byte(toc), -1)
+}
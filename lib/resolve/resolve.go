@@ -0,0 +1,109 @@
+package resolve
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Errors returned when a requested path is refused
+var (
+	ErrInvalid   = errors.New("invalid path")
+	ErrOutside   = errors.New("path escapes the root directory")
+	ErrForbidden = errors.New("file type not allowed")
+)
+
+// Root confines file access to a directory tree, optionally limited to a set of file extensions.
+type Root struct {
+	dir  string
+	exts map[string]bool
+}
+
+// New creates a Root for dir. If exts is not empty, only files with one of those extensions can be resolved.
+func New(dir string, exts []string) (*Root, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	canonical, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(canonical)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.New(dir + " is not a directory")
+	}
+
+	root := &Root{dir: canonical, exts: make(map[string]bool)}
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		root.exts[ext] = true
+	}
+	return root, nil
+}
+
+// Dir returns the canonical absolute path of the root directory.
+func (r *Root) Dir() string {
+	return r.dir
+}
+
+// Resolve maps a slash-separated URL path to a file below the root, returning its path relative to the root. The
+// path is canonicalized with every symlink followed, and refused if it ends up outside the root, if it is malformed,
+// or if its extension is not allowed.
+func (r *Root) Resolve(urlPath string) (string, error) {
+	// Reject anything a browser would never send for a file below the root
+	if strings.ContainsAny(urlPath, "\x00\\") {
+		return "", ErrInvalid
+	}
+	for _, segment := range strings.Split(urlPath, "/") {
+		if segment == ".." {
+			return "", ErrOutside
+		}
+		if strings.Contains(segment, ":") && filepath.VolumeName(segment+`\`) != "" {
+			// Drive letters and other volume names
+			return "", ErrInvalid
+		}
+	}
+
+	cleaned := path.Clean("/" + urlPath)
+	full := filepath.Join(r.dir, filepath.FromSlash(cleaned))
+	canonical, err := filepath.EvalSymlinks(full)
+	if err != nil {
+		return "", err
+	}
+	rel, err := r.rel(canonical)
+	if err != nil {
+		return "", err
+	}
+
+	if len(r.exts) > 0 {
+		info, err := os.Stat(canonical)
+		if err != nil {
+			return "", err
+		}
+		if !info.IsDir() && !r.exts[strings.ToLower(filepath.Ext(canonical))] {
+			return "", ErrForbidden
+		}
+	}
+	return rel, nil
+}
+
+// rel returns the path of canonical relative to the root, or ErrOutside if it is not below the root.
+func (r *Root) rel(canonical string) (string, error) {
+	rel, err := filepath.Rel(r.dir, canonical)
+	if err != nil || filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrOutside
+	}
+	return rel, nil
+}
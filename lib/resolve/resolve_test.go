@@ -0,0 +1,152 @@
+package resolve
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Stands for any error, for paths whose refusal depends on the platform
+var errAny = errors.New("any error")
+
+// newTree creates a root directory next to a directory outside it, each with a few files, and symlinks in the root
+// leading inside and outside it.
+func newTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"root/a.md", "root/sub/b.md", "root/image.PNG", "root/notes.txt", "root/C.MD",
+		"outside/secret.md"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"root/alias.md":     "a.md",
+		"root/leak.md":      filepath.Join(dir, "outside", "secret.md"),
+		"root/relative.md":  filepath.Join("..", "outside", "secret.md"),
+		"root/leakdir":      filepath.Join(dir, "outside"),
+		"root/sub/shortcut": "..",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+	return filepath.Join(dir, "root")
+}
+
+func TestResolve(t *testing.T) {
+	root, err := New(newTree(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want string
+		err  error
+	}{
+		{"a.md", "a.md", nil},
+		{"/a.md", "a.md", nil},
+		{"/sub/b.md", filepath.Join("sub", "b.md"), nil},
+		{"/sub/./b.md", filepath.Join("sub", "b.md"), nil},
+		{"/sub//b.md", filepath.Join("sub", "b.md"), nil},
+		{"/", ".", nil},
+
+		// Parent segments, as given and as decoded
+		{"/../outside/secret.md", "", ErrOutside},
+		{"/sub/../../outside/secret.md", "", ErrOutside},
+		{"/sub/../a.md", "", ErrOutside},
+		{"/..", "", ErrOutside},
+		{"/%2e%2e/outside/secret.md", "", ErrOutside},
+		{"/%2E%2E/%2e%2e/etc/passwd", "", ErrOutside},
+		{"/sub/%2e%2e/%2e%2e/outside/secret.md", "", ErrOutside},
+
+		// Backslashes, as separators on Windows
+		{`/..\outside\secret.md`, "", ErrInvalid},
+		{"/..%5coutside%5csecret.md", "", ErrInvalid},
+		{"/sub%5c..%5c..%5coutside%5csecret.md", "", ErrInvalid},
+
+		// Absolute paths and volume names stay below the root
+		{"/etc/passwd", "", errAny},
+		{"//etc/passwd", "", errAny},
+		{"/C:/Windows/win.ini", "", errAny},
+		{"C:/Windows/win.ini", "", errAny},
+		{"/c:%5cwindows%5cwin.ini", "", ErrInvalid},
+		{`\\server\share\secret.md`, "", ErrInvalid},
+		{"%5c%5cserver%5cshare%5csecret.md", "", ErrInvalid},
+		{"//server/share/secret.md", "", errAny},
+
+		// NUL bytes
+		{"/a.md\x00.png", "", ErrInvalid},
+		{"/a.md%00.png", "", ErrInvalid},
+		{"/%00", "", ErrInvalid},
+
+		// Symlinks are followed, and only allowed if they stay below the root
+		{"/alias.md", "a.md", nil},
+		{"/sub/shortcut/a.md", "a.md", nil},
+		{"/leak.md", "", ErrOutside},
+		{"/relative.md", "", ErrOutside},
+		{"/leakdir", "", ErrOutside},
+		{"/leakdir/secret.md", "", ErrOutside},
+
+		// Missing files
+		{"/missing.md", "", errAny},
+	}
+	for _, tt := range tests {
+		decoded, err := url.PathUnescape(tt.path)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.path, err)
+		}
+		got, err := root.Resolve(decoded)
+		switch {
+		case tt.err == nil && err != nil:
+			t.Errorf("Resolve(%q) = %v, want %q", tt.path, err, tt.want)
+		case tt.err == nil && got != tt.want:
+			t.Errorf("Resolve(%q) = %q, want %q", tt.path, got, tt.want)
+		case tt.err == errAny && err == nil:
+			t.Errorf("Resolve(%q) = %q, want an error", tt.path, got)
+		case tt.err != nil && tt.err != errAny && !errors.Is(err, tt.err):
+			t.Errorf("Resolve(%q) = %q, %v, want %v", tt.path, got, err, tt.err)
+		}
+	}
+}
+
+func TestResolveExtensions(t *testing.T) {
+	root, err := New(newTree(t), []string{".md", " PNG "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		err  error
+	}{
+		{"/a.md", nil},
+		{"/C.MD", nil},
+		{"/image.PNG", nil},
+		{"/notes.txt", ErrForbidden},
+		{"/sub", nil},
+		{"/", nil},
+		{"/alias.md", nil},
+		{"/sub/shortcut", nil},
+		{"/leak.md", ErrOutside},
+		{"/missing.png", errAny},
+	}
+	for _, tt := range tests {
+		_, err := root.Resolve(tt.path)
+		switch {
+		case tt.err == nil && err != nil:
+			t.Errorf("Resolve(%q) = %v, want no error", tt.path, err)
+		case tt.err == errAny && err == nil:
+			t.Errorf("Resolve(%q) succeeded, want an error", tt.path)
+		case tt.err != nil && tt.err != errAny && !errors.Is(err, tt.err):
+			t.Errorf("Resolve(%q) = %v, want %v", tt.path, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package sanitize
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Elements of the MathML produced for TeX math
+var mathElements = []string{"math", "semantics", "annotation", "mrow", "mi", "mo", "mn", "mtext", "mspace", "msup",
+	"msub", "msubsup", "mfrac", "msqrt", "mroot", "munder", "mover", "munderover", "mtable", "mtr", "mtd", "merror"}
+
+// Attributes of MathML elements
+var mathAttributes = []string{"xmlns", "display", "encoding", "mathvariant", "stretchy", "fence", "largeop",
+	"movablelimits", "accent", "accentunder", "minsize", "maxsize", "linethickness", "columnalign", "columnspacing",
+	"width"}
+
+// Elements and attributes of the SVG drawn for diagrams
+var (
+	svgElements   = []string{"svg", "rect", "ellipse", "polygon", "path", "text", "tspan"}
+	svgAttributes = []string{"xmlns", "viewbox", "x", "y", "dy", "width", "height", "rx", "ry", "cx", "cy", "points", "d",
+		"fill", "stroke", "stroke-width", "stroke-dasharray", "opacity", "text-anchor", "dominant-baseline",
+		"font-family", "font-size", "role"}
+)
+
+// Properties of inline styles, as set by syntax highlighting, table cell alignment and diagrams
+var styleProperties = []string{"color", "background-color", "font-weight", "font-style", "text-decoration",
+	"text-align", "display", "width", "max-width", "height", "margin-right", "padding-left", "padding-right",
+	"user-select", "-webkit-user-select"}
+
+// Values of class attributes, which only select styles of the page, and of IDs, which may be derived from headings
+// in any language
+var (
+	className = regexp.MustCompile(`^[a-zA-Z0-9 _:-]*$`)
+	id        = regexp.MustCompile(`^[\p{L}\p{N}_:.-]+$`)
+)
+
+// policy keeps what Markdown renders to, and drops scripts, event handlers, frames, forms and styles that are not
+// inline, among others.
+var policy = newPolicy()
+
+// newPolicy extends the policy for user generated content with the markup of syntax highlighting, math, diagrams,
+// footnotes and task lists.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(className).Globally()
+	p.AllowAttrs("id").Matching(id).Globally()
+	p.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-[a-z]+$`)).Globally()
+	p.AllowStyles(styleProperties...).Globally()
+	p.AllowElements(mathElements...)
+	p.AllowAttrs(mathAttributes...).AllowNoAttrs().OnElements(mathElements...)
+	p.AllowElements(svgElements...)
+	p.AllowAttrs(svgAttributes...).OnElements(svgElements...)
+	p.AllowElements("figure", "input")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// HTML removes from rendered Markdown all markup that could run scripts, such as script elements, event handlers and
+// javascript: URLs.
+func HTML(html []byte) []byte {
+	return policy.SanitizeBytes(html)
+}
+
+// Nonce returns a new random value allowing the scripts that carry it to run.
+func Nonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// FilePolicy is the Content-Security-Policy of files served as they are, which runs them in a sandbox without
+// scripts and without access to the origin of the server, so that an HTML or SVG file cannot act on its behalf.
+const FilePolicy = "sandbox; default-src 'none'"
+
+// ContentSecurityPolicy returns a policy that only lets the scripts carrying nonce run, and only lets the page load
+// images, styles and data from the server itself.
+func ContentSecurityPolicy(nonce string) string {
+	return fmt.Sprintf("default-src 'none'; script-src 'nonce-%s'; style-src 'self' 'unsafe-inline'; "+
+		"img-src 'self' data:; connect-src 'self'; base-uri 'none'; form-action 'self'; frame-ancestors 'none'",
+		nonce)
+}
@@ -0,0 +1,479 @@
+package search
+
+import (
+	"html/template"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	. "github.com/dienakakim/mds/lib/structs"
+)
+
+// Ranking parameters of Okapi BM25, and the extra weight of terms found in titles
+const (
+	k1          = 1.2
+	b           = 0.75
+	titleWeight = 2.0
+)
+
+// Words of at least minPrefix letters also match longer words starting with them, with a lower weight
+const (
+	minPrefix    = 3
+	prefixWeight = 0.5
+)
+
+// Minimum time between two scans of the tree for changed files
+const refreshInterval = time.Second
+
+// Length of snippets, and how much of it comes before the first match, in bytes
+const (
+	snippetLength = 200
+	snippetLead   = 60
+)
+
+// Content is what is indexed of a Markdown file: its title and plain text, and what it links to. Links are
+// slash-separated paths relative to the indexed directory, and WikiLinks are the targets of wiki links as written.
+type Content struct {
+	Title     string
+	Text      string
+	Links     []string
+	WikiLinks []string
+}
+
+// Extractor returns the content of a Markdown file.
+type Extractor func(fileName string, source []byte) Content
+
+// Filter reports whether the file at the slash-separated path p, relative to the indexed directory, may be indexed.
+type Filter func(p string) bool
+
+// Result is a document matching a query.
+type Result struct {
+	Path    string
+	Title   string
+	Snippet template.HTML
+	Score   float64
+}
+
+// document is an indexed Markdown file.
+type document struct {
+	path      string
+	title     string
+	text      string
+	links     []string
+	wikiLinks []string
+	modTime   time.Time
+	size      int64
+	length    int
+	terms     map[string]int
+	titled    map[string]bool
+}
+
+// Index is an inverted index of the Markdown files below a directory. It is brought up to date incrementally, by
+// re-indexing only the files whose modification time or size changed since the last scan. It is safe for concurrent
+// use.
+type Index struct {
+	dir       string
+	extract   Extractor
+	allow     Filter
+	mu        sync.RWMutex
+	docs      map[string]*document
+	postings  map[string]map[string]int
+	names     [3]map[string]map[string]bool
+	total     int
+	version   uint64
+	refreshed time.Time
+}
+
+// New creates an index of the Markdown files below dir that allow lets through, read with extract. A nil allow lets
+// every file through.
+func New(dir string, extract Extractor, allow Filter) *Index {
+	idx := &Index{dir: dir, extract: extract, allow: allow, docs: make(map[string]*document),
+		postings: make(map[string]map[string]int)}
+	for i := range idx.names {
+		idx.names[i] = make(map[string]map[string]bool)
+	}
+	return idx
+}
+
+// Refresh scans the tree, indexing new and changed files and dropping deleted ones.
+func (idx *Index) Refresh() error {
+	seen := make(map[string]bool)
+	err := filepath.Walk(idx.dir, func(fileName string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), ".") && fileName != idx.dir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
+			return nil
+		}
+		rel, err := filepath.Rel(idx.dir, fileName)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if idx.allow != nil && !idx.allow(rel) {
+			return nil
+		}
+		seen[rel] = true
+
+		idx.mu.RLock()
+		doc, ok := idx.docs[rel]
+		idx.mu.RUnlock()
+		if ok && doc.modTime.Equal(info.ModTime()) && doc.size == info.Size() {
+			return nil
+		}
+		source, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return nil
+		}
+		idx.add(newDocument(rel, idx.extract(fileName, source), info))
+		return nil
+	})
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for p := range idx.docs {
+		if !seen[p] {
+			idx.remove(p)
+		}
+	}
+	idx.refreshed = time.Now()
+	return err
+}
+
+// newDocument tokenizes a file for indexing.
+func newDocument(p string, content Content, info os.FileInfo) *document {
+	doc := &document{path: p, title: content.Title, text: content.Text, links: content.Links,
+		wikiLinks: content.WikiLinks, modTime: info.ModTime(), size: info.Size(), terms: make(map[string]int),
+		titled: make(map[string]bool)}
+	for _, t := range tokenize(content.Text) {
+		doc.terms[t.term]++
+		doc.length++
+	}
+	for _, t := range tokenize(content.Title) {
+		doc.titled[t.term] = true
+	}
+	return doc
+}
+
+// add indexes doc, replacing any earlier version of it.
+func (idx *Index) add(doc *document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(doc.path)
+	idx.docs[doc.path] = doc
+	idx.total += doc.length
+	idx.version++
+	for term, count := range doc.terms {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[string]int)
+		}
+		idx.postings[term][doc.path] = count
+	}
+	for i, name := range doc.names() {
+		if name == "" {
+			continue
+		}
+		if idx.names[i][name] == nil {
+			idx.names[i][name] = make(map[string]bool)
+		}
+		idx.names[i][name][doc.path] = true
+	}
+}
+
+// remove drops a document from the index. The caller holds the write lock.
+func (idx *Index) remove(p string) {
+	doc, ok := idx.docs[p]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		delete(idx.postings[term], p)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	for i, name := range doc.names() {
+		delete(idx.names[i][name], p)
+		if len(idx.names[i][name]) == 0 {
+			delete(idx.names[i], name)
+		}
+	}
+	idx.total -= doc.length
+	idx.version++
+	delete(idx.docs, p)
+}
+
+// Update refreshes the index if it has not been scanned recently.
+func (idx *Index) Update() {
+	idx.mu.RLock()
+	stale := time.Since(idx.refreshed) > refreshInterval
+	idx.mu.RUnlock()
+	if stale {
+		idx.Refresh()
+	}
+}
+
+// Version returns a number that changes whenever a document is added, changed or removed.
+func (idx *Index) Version() uint64 {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.version
+}
+
+// Lookup returns the path of the document with the given name, which is matched against paths, file names and
+// titles, ignoring case, the .md extension and the difference between spaces, dashes and underscores. Paths take
+// precedence over file names, and file names over titles.
+func (idx *Index) Lookup(name string) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.lookup(name)
+}
+
+// lookup implements Lookup. The caller holds the read lock.
+func (idx *Index) lookup(name string) (string, bool) {
+	want := normalize(strings.TrimPrefix(name, "/"))
+	for _, names := range idx.names {
+		best := ""
+		for p := range names[want] {
+			if best == "" || p < best {
+				best = p
+			}
+		}
+		if best != "" {
+			return best, true
+		}
+	}
+	return "", false
+}
+
+// names returns the names the document can be looked up by: its path, file name and title, in order of precedence.
+func (doc *document) names() [3]string {
+	title := ""
+	if doc.title != "" {
+		title = normalize(doc.title)
+	}
+	return [3]string{normalize(doc.path), normalize(path.Base(doc.path)), title}
+}
+
+// normalize reduces a document name to the form in which names are compared.
+func normalize(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, ".md"))
+	name = strings.NewReplacer("-", " ", "_", " ").Replace(name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Backlinks returns the other documents linking to the document at p, ordered by title.
+func (idx *Index) Backlinks(p string) []PageLink {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	var backlinks []PageLink
+	for _, doc := range idx.docs {
+		if doc.path != p && idx.linksTo(doc, p) {
+			title := doc.title
+			if title == "" {
+				title = doc.path
+			}
+			backlinks = append(backlinks, PageLink{Path: doc.path, Title: title})
+		}
+	}
+	sort.Slice(backlinks, func(i, j int) bool {
+		if backlinks[i].Title != backlinks[j].Title {
+			return backlinks[i].Title < backlinks[j].Title
+		}
+		return backlinks[i].Path < backlinks[j].Path
+	})
+	return backlinks
+}
+
+// Pages returns every document, ordered by path.
+func (idx *Index) Pages() []PageLink {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	pages := make([]PageLink, 0, len(idx.docs))
+	for _, doc := range idx.docs {
+		title := doc.title
+		if title == "" {
+			title = doc.path
+		}
+		pages = append(pages, PageLink{Path: doc.path, Title: title})
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Path < pages[j].Path })
+	return pages
+}
+
+// linksTo reports whether doc links to the document at p. The caller holds the read lock.
+func (idx *Index) linksTo(doc *document, p string) bool {
+	for _, l := range doc.links {
+		if l == p {
+			return true
+		}
+	}
+	for _, name := range doc.wikiLinks {
+		if found, ok := idx.lookup(name); ok && found == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Search returns up to limit documents containing any of the words of query, best first. The index is refreshed
+// first if it has not been scanned recently.
+func (idx *Index) Search(query string, limit int) []Result {
+	idx.Update()
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if len(idx.docs) == 0 {
+		return nil
+	}
+	terms := idx.expand(query)
+	average := float64(idx.total) / float64(len(idx.docs))
+	scores := make(map[string]float64)
+	for term, weight := range terms {
+		posting := idx.postings[term]
+		idf := math.Log(1 + (float64(len(idx.docs))-float64(len(posting))+0.5)/(float64(len(posting))+0.5))
+		for p, count := range posting {
+			tf := float64(count)
+			norm := 1 - b + b*float64(idx.docs[p].length)/average
+			score := weight * idf * tf * (k1 + 1) / (tf + k1*norm)
+			if idx.docs[p].titled[term] {
+				score *= titleWeight
+			}
+			scores[p] += score
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for p, score := range scores {
+		doc := idx.docs[p]
+		title := doc.title
+		if title == "" {
+			title = p
+		}
+		results = append(results, Result{Path: p, Title: title, Snippet: snippet(doc.text, terms), Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Path < results[j].Path
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// expand returns the indexed terms matching the words of query, with their weights: words match themselves, and
+// words long enough also match longer terms starting with them, with a lower weight. The caller holds the read lock.
+func (idx *Index) expand(query string) map[string]float64 {
+	terms := make(map[string]float64)
+	for _, t := range tokenize(query) {
+		if _, ok := idx.postings[t.term]; ok {
+			terms[t.term] = 1
+		}
+		if utf8.RuneCountInString(t.term) < minPrefix {
+			continue
+		}
+		for term := range idx.postings {
+			if term != t.term && strings.HasPrefix(term, t.term) && terms[term] < prefixWeight {
+				terms[term] = prefixWeight
+			}
+		}
+	}
+	return terms
+}
+
+// token is a normalized word and where it appears in the text.
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits text into lower case words of letters and digits.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text + " " {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	return tokens
+}
+
+// snippet returns the part of text around the first of the given terms, with every occurrence of them marked.
+func snippet(text string, terms map[string]float64) template.HTML {
+	tokens := tokenize(text)
+	from := 0
+	for _, t := range tokens {
+		if terms[t.term] > 0 {
+			from = t.start - snippetLead
+			break
+		}
+	}
+	if from < 0 {
+		from = 0
+	}
+	to := from + snippetLength
+	if to > len(text) {
+		to = len(text)
+	}
+	// Do not cut words or characters in half
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	for _, t := range tokens {
+		if t.start < from && t.end > from {
+			from = t.end
+		}
+		if t.start < to && t.end > to {
+			to = t.start
+		}
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+
+	var s strings.Builder
+	if from > 0 {
+		s.WriteString("… ")
+	}
+	pos := from
+	for _, t := range tokens {
+		if t.start < from || t.end > to || terms[t.term] == 0 {
+			continue
+		}
+		s.WriteString(template.HTMLEscapeString(text[pos:t.start]))
+		s.WriteString("<mark>")
+		s.WriteString(template.HTMLEscapeString(text[t.start:t.end]))
+		s.WriteString("</mark>")
+		pos = t.end
+	}
+	s.WriteString(template.HTMLEscapeString(text[pos:to]))
+	if to < len(text) {
+		s.WriteString(" …")
+	}
+	return template.HTML(strings.TrimSpace(s.String()))
+}
@@ -0,0 +1,35 @@
+package settings
+
+import (
+	"sync"
+
+	. "github.com/dienakakim/mds/lib/structs"
+)
+
+// Store holds the runtime settings of the server. It is safe for concurrent use, so the pause menu can change
+// settings while requests are being served.
+type Store struct {
+	mu     sync.RWMutex
+	config Config
+}
+
+// New creates a Store holding the initial configuration.
+func New(config Config) *Store {
+	return &Store{config: config}
+}
+
+// Snapshot returns a copy of the current settings. Requests render from their own snapshot, so later changes never
+// affect a request in flight.
+func (s *Store) Snapshot() Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// Update changes the settings atomically and returns the new ones.
+func (s *Store) Update(change func(config *Config)) Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change(&s.config)
+	return s.config
+}
@@ -1,9 +1,20 @@
 package structs
 
-// Config saves the current configuration of this server run.
+import "html/template"
+
+// Config saves the current configuration of this server run. It is passed by value, so every request renders from
+// its own copy. Served is only set by the server, for controls that need it to answer, unlike exported pages.
 type Config struct {
 	DarkMode   bool
 	FileName   string
-	MathJax    bool
-	StyleBytes []byte
+	LiveReload bool
+	Math       bool
+	Style      template.CSS
+	Theme      string
+	Search     bool
+	Safe       bool
+	Control    bool
+	Nav        bool
+	Mermaid    bool
+	Served     bool
 }
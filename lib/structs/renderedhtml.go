@@ -4,7 +4,41 @@ import "html/template"
 
 // RenderedHTML is the template struct used for the templating engine.
 type RenderedHTML struct {
-	Body     template.HTML
-	Style    template.CSS
-	FileName string
+	Body       template.HTML
+	Style      template.CSS
+	FileName   string
+	Title      string
+	Path       string
+	LiveReload bool
+	Theme      string
+	Dark       bool
+	TOC        template.HTML
+	Meta       map[string]interface{}
+	Search     bool
+	Query      string
+	Backlinks  []PageLink
+	Print      bool
+	Nonce      string
+	Control    bool
+	Nav        []NavItem
+	Prev       *PageLink
+	Next       *PageLink
+	Mermaid    bool
+	Served     bool
+}
+
+// PageLink is a link to another document, shown with its title.
+type PageLink struct {
+	Path  string
+	Title string
+}
+
+// NavItem is an entry of the navigation sidebar, with the entries below it nested inside. Path is empty for entries
+// that only group others, Current marks the page being shown and Open the entries containing it.
+type NavItem struct {
+	Title    string
+	Path     string
+	Current  bool
+	Open     bool
+	Children []NavItem
 }
@@ -0,0 +1,70 @@
+package theme
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+
+	"github.com/yuin/goldmark"
+)
+
+// Name of the cookie remembering the theme chosen by a browser
+const cookieName = "mds_theme"
+
+// Theme pairs a stylesheet with the syntax highlighting style matching it.
+type Theme struct {
+	Name           string
+	Dark           bool
+	Style          template.CSS
+	HighlightStyle string
+	Markdown       goldmark.Markdown
+}
+
+// Registry holds every theme that can be selected by name.
+type Registry struct {
+	themes map[string]*Theme
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{themes: make(map[string]*Theme)}
+}
+
+// Add registers a theme, replacing any theme of the same name.
+func (r *Registry) Add(t *Theme) {
+	r.themes[t.Name] = t
+}
+
+// Get returns the theme registered under name.
+func (r *Registry) Get(name string) (*Theme, bool) {
+	t, ok := r.themes[name]
+	return t, ok
+}
+
+// Names returns the names of all registered themes in alphabetical order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.themes))
+	for name := range r.themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Select picks the theme for a request. A known theme in the "theme" query parameter wins and is remembered in a
+// cookie; otherwise the theme remembered by the cookie is used, falling back to the theme named fallback.
+func (r *Registry) Select(w http.ResponseWriter, req *http.Request, fallback string) *Theme {
+	if name := req.URL.Query().Get("theme"); name != "" {
+		if t, ok := r.themes[name]; ok {
+			http.SetCookie(w, &http.Cookie{Name: cookieName, Value: name, Path: "/", MaxAge: 365 * 24 * 60 * 60,
+				SameSite: http.SameSiteLaxMode})
+			return t
+		}
+	}
+	if c, err := req.Cookie(cookieName); err == nil {
+		if t, ok := r.themes[c.Value]; ok {
+			return t
+		}
+	}
+	return r.themes[fallback]
+}
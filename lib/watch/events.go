@@ -0,0 +1,83 @@
+package watch
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// Interval between keep-alive comments sent on idle event streams
+const keepAlive = 30 * time.Second
+
+// EventsHandler streams Server-Sent Events to browsers displaying a file. The file is taken from the "file" query
+// parameter and checked with resolve, and linked is used to find the local files it links to, which are watched
+// alongside it if resolve accepts them too. A "change" event carrying the changed path is sent whenever any of them is
+// modified.
+func EventsHandler(w *Watcher, resolve func(urlPath string) (string, error), linked func(fileName string) []string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		flusher, ok := rw.(http.Flusher)
+		if !ok {
+			http.Error(rw, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		requested := r.URL.Query().Get("file")
+		fileName, err := resolve(requested)
+		if err != nil || fileName == "." {
+			http.Error(rw, fmt.Sprintf("Bad Request: cannot watch \"%s\"", requested), http.StatusBadRequest)
+			return
+		}
+
+		// Collect the set of files relevant to this page, watched for as long as the page is open
+		events := w.Subscribe()
+		defer w.Unsubscribe(events)
+		watched := make(map[string]bool)
+		collect := func() {
+			watched = map[string]bool{fileName: true}
+			for _, l := range linked(fileName) {
+				if p, err := resolve(filepath.ToSlash(l)); err == nil {
+					watched[p] = true
+				}
+			}
+			paths := make([]string, 0, len(watched))
+			for p := range watched {
+				paths = append(paths, p)
+			}
+			w.Watch(events, paths...)
+		}
+		collect()
+
+		rw.Header().Set("Content-Type", "text/event-stream")
+		rw.Header().Set("Cache-Control", "no-cache")
+		rw.Header().Set("Connection", "keep-alive")
+		rw.WriteHeader(http.StatusOK)
+		flusher.Flush()
+		log.Printf("Live reload attached to \"%s\"", fileName)
+
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-w.done:
+				// The server is shutting down
+				return
+			case <-ticker.C:
+				fmt.Fprint(rw, ": keep-alive\n\n")
+				flusher.Flush()
+			case changed := <-events:
+				if !watched[changed] {
+					continue
+				}
+				if changed == fileName {
+					// Links may have been added or removed
+					collect()
+				}
+				fmt.Fprintf(rw, "event: change\ndata: %s\n\n", filepath.ToSlash(changed))
+				flusher.Flush()
+			}
+		}
+	}
+}
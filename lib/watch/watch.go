@@ -0,0 +1,149 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileState is the last observed state of a watched file, and the number of subscribers watching it.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+	refs    int
+}
+
+// Watcher polls the files its subscribers watch for modifications and notifies them of every change. A file is only
+// polled as long as a subscriber watches it.
+type Watcher struct {
+	mu       sync.Mutex
+	interval time.Duration
+	files    map[string]fileState
+	subs     map[chan string]map[string]bool
+	done     chan bool
+	once     sync.Once
+}
+
+// New creates a Watcher polling every interval and starts it.
+func New(interval time.Duration) *Watcher {
+	w := &Watcher{
+		interval: interval,
+		files:    make(map[string]fileState),
+		subs:     make(map[chan string]map[string]bool),
+		done:     make(chan bool),
+	}
+	go w.run()
+	return w
+}
+
+// Subscribe returns a channel receiving the path of every file it watches that changes.
+func (w *Watcher) Subscribe() chan string {
+	ch := make(chan string, 16)
+	w.mu.Lock()
+	w.subs[ch] = make(map[string]bool)
+	w.mu.Unlock()
+	return ch
+}
+
+// Watch makes paths the files watched for the subscriber ch, instead of those it watched before. Files already
+// watched for others are left untouched, and files no subscriber watches any more are dropped.
+func (w *Watcher) Watch(ch chan string, paths ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	old, ok := w.subs[ch]
+	if !ok {
+		return
+	}
+	watched := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		watched[filepath.Clean(p)] = true
+	}
+	for p := range watched {
+		if !old[p] {
+			w.ref(p, 1)
+		}
+	}
+	for p := range old {
+		if !watched[p] {
+			w.ref(p, -1)
+		}
+	}
+	w.subs[ch] = watched
+}
+
+// Unsubscribe stops delivering changes to ch, and stops watching the files only it watched.
+func (w *Watcher) Unsubscribe(ch chan string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for p := range w.subs[ch] {
+		w.ref(p, -1)
+	}
+	delete(w.subs, ch)
+}
+
+// ref counts one subscriber more or less watching the file at p. The caller holds the lock.
+func (w *Watcher) ref(p string, delta int) {
+	state, ok := w.files[p]
+	if !ok {
+		state = stat(p)
+	}
+	if state.refs += delta; state.refs <= 0 {
+		delete(w.files, p)
+		return
+	}
+	w.files[p] = state
+}
+
+// Close stops polling.
+func (w *Watcher) Close() {
+	w.once.Do(func() { close(w.done) })
+}
+
+// run polls every watched file until the Watcher is closed.
+func (w *Watcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// poll compares the current state of each file against the last observed one.
+func (w *Watcher) poll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for p, old := range w.files {
+		cur := stat(p)
+		if cur.exists == old.exists && cur.size == old.size && cur.modTime.Equal(old.modTime) {
+			continue
+		}
+		cur.refs = old.refs
+		w.files[p] = cur
+		for ch, watched := range w.subs {
+			if !watched[p] {
+				continue
+			}
+			select {
+			case ch <- p:
+			default:
+				// Slow subscriber; it will catch up on the next change
+			}
+		}
+	}
+}
+
+// stat returns the current state of the file at p.
+func stat(p string) fileState {
+	info, err := os.Stat(p)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
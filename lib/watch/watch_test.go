@@ -0,0 +1,80 @@
+package watch
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// polled returns the files w polls.
+func polled(w *Watcher) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var paths []string
+	for p := range w.files {
+		paths = append(paths, filepath.Base(p))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestWatchDropsUnwatchedFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	w := New(time.Hour)
+	defer w.Close()
+
+	a, b := w.Subscribe(), w.Subscribe()
+	w.Watch(a, file("page.md"), file("linked.md"))
+	w.Watch(b, file("page.md"), file("other.md"))
+	if got := polled(w); len(got) != 3 {
+		t.Fatalf("polled %v, want linked.md, other.md and page.md", got)
+	}
+
+	// A link removed from the page
+	w.Watch(a, file("page.md"))
+	if got := polled(w); len(got) != 2 || got[0] != "other.md" || got[1] != "page.md" {
+		t.Fatalf("polled %v after removing a link, want other.md and page.md", got)
+	}
+
+	w.Unsubscribe(b)
+	if got := polled(w); len(got) != 1 || got[0] != "page.md" {
+		t.Fatalf("polled %v after a stream closed, want page.md", got)
+	}
+	w.Unsubscribe(a)
+	if got := polled(w); len(got) != 0 {
+		t.Fatalf("polled %v after every stream closed, want nothing", got)
+	}
+}
+
+func TestWatchNotifiesWatchingSubscribers(t *testing.T) {
+	dir := t.TempDir()
+	page, other := filepath.Join(dir, "page.md"), filepath.Join(dir, "other.md")
+	w := New(time.Hour)
+	defer w.Close()
+
+	a, b := w.Subscribe(), w.Subscribe()
+	w.Watch(a, page)
+	w.Watch(b, other)
+	if err := ioutil.WriteFile(page, []byte("# Page\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.poll()
+	select {
+	case changed := <-a:
+		if changed != page {
+			t.Errorf("got change of %s, want %s", changed, page)
+		}
+	default:
+		t.Error("no change delivered for a watched file")
+	}
+	select {
+	case changed := <-b:
+		t.Errorf("got change of %s, which is not watched", changed)
+	default:
+	}
+}
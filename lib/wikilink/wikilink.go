@@ -0,0 +1,114 @@
+package wikilink
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Attribute holding the target of a wiki link as written, so that it can be told apart from ordinary links
+const TargetAttribute = "data-wikilink"
+
+// Classes of wiki links, and of those pointing to no document
+const (
+	linkClass   = "wikilink"
+	brokenClass = "wikilink broken text-red-500"
+)
+
+// Resolver finds the document a wiki link points to.
+type Resolver interface {
+	// Lookup returns the slash-separated path, relative to the served root, of the document with the given title or
+	// file name.
+	Lookup(name string) (string, bool)
+}
+
+// wikiLinkParser parses [[Page]] and [[Page|label]] into links to local documents.
+type wikiLinkParser struct {
+	resolver Resolver
+}
+
+// Trigger implements parser.InlineParser.
+func (p *wikiLinkParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+// Parse implements parser.InlineParser.
+func (p *wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	if !bytes.HasPrefix(line, []byte("[[")) {
+		return nil
+	}
+	end := bytes.Index(line[2:], []byte("]]"))
+	if end < 0 {
+		return nil
+	}
+	inner := line[2 : 2+end]
+	if len(bytes.TrimSpace(inner)) == 0 || bytes.ContainsAny(inner, "[]\n") {
+		return nil
+	}
+
+	// The label is the part after |, or else the target itself
+	target, labelStart := inner, 2
+	if i := bytes.IndexByte(inner, '|'); i >= 0 {
+		target, labelStart = inner[:i], 2+i+1
+	}
+	labelStop := 2 + end
+	for labelStart < labelStop && line[labelStart] == ' ' {
+		labelStart++
+	}
+	for labelStop > labelStart && line[labelStop-1] == ' ' {
+		labelStop--
+	}
+	name := strings.TrimSpace(string(target))
+	if name == "" || labelStart == labelStop {
+		return nil
+	}
+	block.Advance(2 + end + 2)
+
+	link := ast.NewLink()
+	link.SetAttributeString(TargetAttribute, []byte(name))
+	page, fragment := name, ""
+	if i := strings.IndexByte(name, '#'); i >= 0 {
+		// Written the way headings read, so matched against automatic heading IDs
+		page, fragment = name[:i], "#"+strings.Join(strings.Fields(strings.ToLower(name[i+1:])), "-")
+	}
+	if page == "" {
+		// Heading in the same document
+		link.Destination = []byte(fragment)
+		link.SetAttributeString("class", []byte(linkClass))
+	} else if p.resolver != nil {
+		if found, ok := p.resolver.Lookup(page); ok {
+			link.Destination = []byte("/" + found + fragment)
+			link.SetAttributeString("class", []byte(linkClass))
+		}
+	}
+	if link.Destination == nil {
+		link.Destination = []byte("/" + page + ".md")
+		link.Title = []byte("No page named " + page)
+		link.SetAttributeString("class", []byte(brokenClass))
+	}
+	link.AppendChild(link, ast.NewTextSegment(text.NewSegment(segment.Start+labelStart, segment.Start+labelStop)))
+	return link
+}
+
+// wikiLinkExtension adds wiki links to goldmark.
+type wikiLinkExtension struct {
+	resolver Resolver
+}
+
+// New returns the goldmark extension turning [[Page]] and [[Page|label]] into links to the documents found by
+// resolver. Links to missing documents get the "broken" class. A nil resolver leaves every link broken.
+func New(resolver Resolver) goldmark.Extender {
+	return &wikiLinkExtension{resolver: resolver}
+}
+
+// Extend implements goldmark.Extender.
+func (e *wikiLinkExtension) Extend(m goldmark.Markdown) {
+	// Ahead of the link parser, which would otherwise take the outer brackets
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(&wikiLinkParser{resolver: e.resolver}, 199)))
+}
@@ -3,115 +3,344 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"html/template"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/dienakakim/mds/lib/api"
+	"github.com/dienakakim/mds/lib/auth"
+	"github.com/dienakakim/mds/lib/cache"
+	"github.com/dienakakim/mds/lib/certs"
+	"github.com/dienakakim/mds/lib/diagram"
+	"github.com/dienakakim/mds/lib/mathml"
+	"github.com/dienakakim/mds/lib/options"
 	. "github.com/dienakakim/mds/lib/render"
+	"github.com/dienakakim/mds/lib/resolve"
+	"github.com/dienakakim/mds/lib/search"
+	"github.com/dienakakim/mds/lib/settings"
 	. "github.com/dienakakim/mds/lib/structs"
-	mathjax "github.com/litao91/goldmark-mathjax"
+	"github.com/dienakakim/mds/lib/theme"
+	"github.com/dienakakim/mds/lib/watch"
+	"github.com/dienakakim/mds/lib/wikilink"
 	"github.com/yuin/goldmark"
+	emoji "github.com/yuin/goldmark-emoji"
 	highlighting "github.com/yuin/goldmark-highlighting"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
+	"golang.org/x/term"
 )
 
 // Help text
 var helpText = `
 Usage: ${prog} --file=FILE.md
        ${prog} --port 3000 --file=FILE.md
+       ${prog} export --src=DIR --out=DIR
+       ${prog} check [--json] [--external] --src=DIR
+       ${prog} print --file=FILE.md [--out=FILE.pdf|FILE.html]
+       ${prog} ctl [--port=8080 | --socket=PATH] [--tls] status|dark|file FILE.md|reload|files
 
+    --file      File shown at /; by default the README.md or index.md of the
+                root, or a listing of the root if there is neither
     --port      Port to serve from
-    --dark      Display in dark theme
+    --bind      Address to listen on, 127.0.0.1 by default so that only this
+                computer can connect; 0.0.0.0 for every interface, or
+                unix:PATH for a Unix socket
+    --tls       Serve HTTPS and HTTP/2, with a self-signed certificate kept
+                in the user config directory unless --cert and --key are given
+    --cert      TLS certificate file, PEM encoded
+    --key       TLS private key file, PEM encoded
+    --root      Directory to serve; nothing outside it is ever read
+    --ext       Comma-separated list of the only file extensions served,
+                e.g. .md,.png,.jpg
+    --dark      Display in dark theme by default; browsers can pick
+                another theme with ?theme=dark|light|monokai|dracula|
+                github|solarized-light
+    --theme     Theme used by default, overriding --dark
+    --highlight Highlighting style per theme, e.g. light:github,dark:monokai
+    --extensions
+                Comma-separated list of the Markdown extensions to enable,
+                out of tables, strikethrough, tasklists, linkify (all four
+                together: gfm), footnotes, definitionlists, typographer,
+                emoji, cjk, highlighting, diagrams and wikilinks; default
+                gfm,footnotes,highlighting,diagrams,wikilinks
+    --html      Render raw HTML in documents instead of omitting it
+    --safe      Strip scripts and other active content from rendered
+                documents, for previewing Markdown from untrusted sources
+    --live      Reload the page when the file changes
+    --nav       Show a sidebar of all Markdown files, arranged by a
+                SUMMARY.md or _sidebar.md in the root if there is one
+    --cache     Memory for rendered pages, in megabytes (0 disables)
+    --template  Use a custom page template instead of the built-in one
+    --css       Use a custom stylesheet instead of the built-in ones
+    --math      Render TeX math as MathML
+    --mermaid   Local copy of mermaid.min.js (version 10 or later), served to
+                draw the Mermaid diagram types not drawn on the server, such as
+                Git graphs and timelines, in the browser
+    --api-token Token required by the API at /_mds/api; a random one is
+                printed at startup by default
+    --htpasswd  Only let in the users of an htpasswd file with bcrypt
+                hashes, as made by htpasswd -B, through HTTP Basic auth
+    --access-token
+                Only let in browsers that opened a link with the random
+                token printed at startup, e.g. /?token=...
+    --no-interactive
+                Shut down on Ctrl+C instead of offering the pause menu,
+                which is only ever offered when stdin is a terminal
+    --shutdown-timeout
+                Time given to requests in flight to complete on exit,
+                e.g. 30s; SIGTERM always exits this way
+    --print-config
+                Show the settings in effect and where each comes from
     --help      Show this help screen
+
+Settings are also read from MDS_PORT-style environment variables, then from
+mds.yaml, mds.yml or .mdsrc in the working directory, then from the same file
+in the mds folder of the user config directory. Flags take precedence.
+The export, check and print modes take --math, --html, --extensions and
+--highlight too, and read them from the same places.
 `
 
+// Maximum number of search results shown
+const maxResults = 50
+
 // main is the driver code for the program.
 func main() {
-	// Flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			exportMain(os.Args[2:])
+			return
+		case "check":
+			checkMain(os.Args[2:])
+			return
+		case "print":
+			printMain(os.Args[2:])
+			return
+		case "ctl":
+			ctlMain(os.Args[2:])
+			return
+		}
+	}
+
+	// Flags, also read from configuration files and environment variables
 	help := flag.Bool("help", false, "show help")
+	printConfig := flag.Bool("print-config", false, "print the configuration and exit")
 	dark := flag.Bool("dark", true, "enable dark theme")
-	mathMode := flag.Bool("math", true, "enable MathJax")
+	defaultTheme := flag.String("theme", "", "default theme")
+	markdownSettings := markdownFlags(flag.CommandLine)
+	safe := flag.Bool("safe", false, "sanitize rendered documents")
+	live := flag.Bool("live", true, "enable live reload")
+	navigation := flag.Bool("nav", true, "show a sidebar of all pages")
+	bind := flag.String("bind", "127.0.0.1", "address to listen on")
+	tlsOn := flag.Bool("tls", false, "serve HTTPS")
+	certFile := flag.String("cert", "", "TLS certificate file")
+	keyFile := flag.String("key", "", "TLS key file")
 	port := flag.String("port", "8080", "server port")
 	file := flag.String("file", "", "filename")
-	flag.Parse()
+	rootDir := flag.String("root", ".", "served root directory")
+	exts := flag.String("ext", "", "allowed file extensions")
+	cacheSize := flag.Int64("cache", 64, "render cache size in megabytes")
+	templateFile := flag.String("template", "", "page template file")
+	cssFile := flag.String("css", "", "stylesheet file")
+	mermaidFile := flag.String("mermaid", "", "local copy of mermaid.min.js")
+	apiToken := flag.String("api-token", "", "token required by the API")
+	htpasswd := flag.String("htpasswd", "", "htpasswd file of the users allowed in")
+	accessToken := flag.Bool("access-token", false, "require a random token printed at startup")
+	noInteractive := flag.Bool("no-interactive", false, "never offer the pause menu")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "time given to requests to complete on exit")
+	// The project configuration file is looked for here even after moving to the root
+	workDir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	sources, err := options.Load(flag.CommandLine, workDir, os.Args[1:], "help", "print-config")
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	if *help {
 		usage("")
 		os.Exit(0)
 	}
+	if *printConfig {
+		options.Print(os.Stdout, flag.CommandLine, sources)
+		os.Exit(0)
+	}
 	if *file == "" {
-		*file = "index.md"
-		fmt.Printf("Filename not specified -- defaulting to \"index.md\"\n\n")
+		// The root directory, shown through its README.md or index.md, or listed
+		*file = "."
+		fmt.Printf("Filename not specified -- serving the root directory\n\n")
+	}
+	markdown, err := markdownSettings()
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	config := Config{DarkMode: *dark, FileName: *file, MathJax: *mathMode}
+	// Mermaid diagrams that are not drawn on the server are drawn in the browser, if a script was given for it
+	var mermaidScript []byte
+	if *mermaidFile != "" {
+		if mermaidScript, err = ioutil.ReadFile(*mermaidFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	config := Config{DarkMode: *dark, FileName: *file, LiveReload: *live, Math: markdown.Math, Style: stylesheet(*dark),
+		Theme: *defaultTheme, Search: true, Safe: *safe, Control: true, Nav: *navigation, Mermaid: mermaidScript != nil,
+		Served: true}
+	store := settings.New(config)
 
 	// Create template
-	var htmlTemplateBytes []byte
-	if *dark {
-		styleBytes := MustAsset("assets/dark.out.css")
-		config.StyleBytes = styleBytes
-	} else {
-		styleBytes := MustAsset("assets/light.out.css")
-		config.StyleBytes = styleBytes
-	}
-	htmlTemplateBytes = MustAsset("assets/index.gohtml")
-	templ, err := template.New("md").Parse(string(htmlTemplateBytes))
+	templ, err := loadTemplate(*templateFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	css, err := loadStyle(*cssFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Listen before leaving the working directory, which relative socket and certificate paths refer to. HTTP/2 is
+	// enabled along with TLS.
+	if (*certFile == "") != (*keyFile == "") {
+		log.Fatal("--cert and --key go together")
+	}
+	listener, err := listen(*bind, *port)
 	if err != nil {
 		log.Fatal(err)
 	}
+	// The flags keep their values as given, so that reloading the configuration does not see them as changed
+	certPath, keyPath := *certFile, *keyFile
+	useTLS := *tlsOn || certPath != "" || keyPath != ""
+	if useTLS && certPath == "" {
+		if certPath, keyPath, err = certs.SelfSigned(certificateHosts(*bind)); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Using the self-signed certificate %s", certPath)
+	}
+	for _, f := range []*string{&certPath, &keyPath} {
+		if *f == "" {
+			continue
+		}
+		if *f, err = filepath.Abs(*f); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	// goldmarkInitializer will initialize Goldmark with:
-	// - GitHub Flavored Markdown
-	// - MathJax
-	// - Appropriate styling for given theme
-	// - Allow custom HTML
-	// - Auto heading ID generation
-	goldmarkInitializer := func(style string) goldmark.Markdown {
-		return goldmark.New(goldmark.WithExtensions(extension.GFM, mathjax.MathJax, highlighting.NewHighlighting(highlighting.WithStyle(style))), goldmark.WithRendererOptions(html.WithUnsafe()),
-			goldmark.WithParserOptions(parser.WithAutoHeadingID(), parser.WithHeadingAttribute()))
+	// Users are read from the working directory too
+	if *htpasswd != "" && *accessToken {
+		log.Fatal("--htpasswd and --access-token cannot be used together")
+	}
+	var users *auth.Users
+	if *htpasswd != "" {
+		if users, err = auth.LoadUsers(*htpasswd); err != nil {
+			log.Fatal(err)
+		}
 	}
-	gmLight := goldmarkInitializer("monokailight")
-	gmDark := goldmarkInitializer("solarized-dark")
+
+	// Serve paths relative to the root from now on
+	var allowed []string
+	if *exts != "" {
+		allowed = strings.Split(*exts, ",")
+	}
+	root, err := resolve.New(*rootDir, allowed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Chdir(root.Dir()); err != nil {
+		log.Fatal(err)
+	}
+
+	// Documents are indexed for search and for links between them, which need no highlighting, math or diagrams
+	index := newIndex(".", root)
+	themes := newThemes(markdown, css, index)
+	if _, ok := themes.Get(config.Theme); config.Theme != "" && !ok {
+		log.Fatalf("Unknown theme %q, expected one of %s", config.Theme, strings.Join(themes.Names(), ", "))
+	}
+	pages := cache.New(*cacheSize << 20)
+	control := newControl(store, root, themes, workDir)
 
 	// Create new ServeMux
 	sm := http.NewServeMux()
-	sm.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		unescapedFileName, _ := url.QueryUnescape(filepath.Clean(r.URL.String()))
-		fileName := filepath.ToSlash(unescapedFileName)
-		log.Printf("%s %s", r.Method, fileName)
-
-		// Get pathname
-		if r.URL.String() == "/" {
-			config.FileName = *file
-		} else {
-			// Check if URL attempts to escape from current directory
-			if strings.Contains(fileName, "/../") {
-				log.Printf("Malicious access: %s", r.URL)
-				w.WriteHeader(http.StatusBadRequest)
-				err := fmt.Sprintf("Bad Request: \"%s\" attempts to escape current directory", config.FileName)
-				fmt.Fprintln(w, err)
+	sm.HandleFunc("/", serveFiles(store, root, themes, templ, pages, index))
+	sm.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s", r.Method, r.URL)
+		config := store.Snapshot()
+		selectTheme(themes, w, r, &config)
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		var results []search.Result
+		if query != "" {
+			results = index.Search(query, maxResults)
+		}
+		RenderSearch(w, r, templ, config, query, results)
+	})
+	// Printable versions of a document, always in the light theme
+	printable := func(asPDF bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			log.Printf("%s %s", r.Method, r.URL)
+			config := store.Snapshot()
+			requested := r.URL.Query().Get("file")
+			if requested == "" {
+				requested = filepath.ToSlash(config.FileName)
+			}
+			fileName, err := root.Resolve(requested)
+			if err != nil {
+				refuse(w, r, requested, err)
 				return
-			} else {
-				config.FileName = fileName[1:]
 			}
+			light, _ := themes.Get("light")
+			config.FileName, config.Theme = fileName, light.Name
+			config.DarkMode, config.Style = false, light.Style
+			RenderPrint(w, light.Markdown, templ, config, asPDF, root.Resolve)
 		}
-
-		if config.DarkMode {
-			Render(w, r, gmDark, templ, config)
-		} else {
-			Render(w, r, gmLight, templ, config)
-		}
+	}
+	sm.HandleFunc("/export.html", printable(false))
+	sm.HandleFunc("/export.pdf", printable(true))
+	sm.HandleFunc("/_mds/cache", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(pages.Stats())
+	})
+	if mermaidScript != nil {
+		sm.HandleFunc("/_mds/mermaid.js", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+			w.Header().Set("Cache-Control", "max-age=3600")
+			w.Write(mermaidScript)
+		})
+	}
+	sm.HandleFunc("/_mds/config", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(markdown)
 	})
+	// Remote control, for local clients through the token file
+	token := *apiToken
+	if token == "" {
+		token = api.NewToken()
+		log.Printf("API token: %s", token)
+	}
+	instance := *port
+	if socket := strings.TrimPrefix(*bind, "unix:"); socket != *bind {
+		instance = "unix-" + filepath.Base(socket)
+	}
+	tokenFile, err := api.SaveToken(instance, token)
+	if err != nil {
+		log.Printf("API token not saved: %v", err)
+	}
+	sm.HandleFunc(api.Prefix, api.Handler(token, control))
+	sm.HandleFunc(api.Prefix+"/", api.Handler(token, control))
 	sm.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("%s %s", r.Method, r.URL)
 		w.Header().Set("Content-Type", "image/x-icon")
@@ -120,78 +349,86 @@ func main() {
 		return
 	})
 
-	// Initialize signal handler
+	// Everything but the API, which has its own token, may need users to sign in
+	var handler http.Handler = sm
+	switch {
+	case users != nil:
+		handler = auth.Except(api.Prefix, sm, auth.Basic(users, sm))
+	case *accessToken:
+		access := api.NewToken()
+		log.Printf("Access token: %s (open /?token=%s once to sign in)", access, access)
+		handler = auth.Except(api.Prefix, sm, auth.Token(access, sm))
+	}
+	server := &http.Server{Handler: handler, TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12}}
+	if *live {
+		// Watch served files and notify browsers of changes. Closing the watcher ends their event streams.
+		watcher := watch.New(500 * time.Millisecond)
+		server.RegisterOnShutdown(watcher.Close)
+		sm.HandleFunc("/_mds/events", watch.EventsHandler(watcher, root.Resolve, func(fileName string) []string {
+			content, err := ioutil.ReadFile(fileName)
+			if err != nil {
+				return nil
+			}
+			light, _ := themes.Get("light")
+			return LocalLinks(light.Markdown, fileName, content)
+		}))
+	}
+
+	// Initialize signal handler. The pause menu is only offered to someone at a terminal, and any other interrupt
+	// shuts the server down. The menu waits for input on its own, so that SIGTERM is acted on even meanwhile.
+	interactive := !*noInteractive && term.IsTerminal(int(os.Stdin.Fd()))
 	signals := make(chan os.Signal, 1)
 	done := make(chan bool)
-	signal.Notify(signals, os.Interrupt, os.Kill)
-	paused := false
-	go func(config *Config) {
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		paused := false
+		chosen := make(chan bool, 1)
 		for {
-			sig := <-signals
-			switch sig {
-			case os.Interrupt:
-				if !paused {
-					// Pause menu
-					paused = true
-					log.Println("Paused.")
-					fmt.Println("Choose one of the below options:")
-					fmt.Println("1. Toggle dark mode")
-					fmt.Println("2. Change filename")
-					fmt.Println("3. Exit")
-					fmt.Printf("> ")
-					var choiceStr string
-					fmt.Scanln(&choiceStr)
-					choiceStr = strings.TrimSpace(choiceStr)
-
-					switch choiceStr {
-					case "1":
-						// Toggle dark mode
-						config.DarkMode = !config.DarkMode
-						status := "enabled"
-						if config.DarkMode {
-							config.StyleBytes, _ = assetsDarkOutCssBytes()
-						} else {
-							status = "disabled"
-							config.StyleBytes, _ = assetsLightOutCssBytes()
-						}
-						log.Printf("Dark mode %s", status)
-						paused = false
-					case "2":
-						// Change filename
-						fmt.Println("Enter in new Markdown filename: ")
-						fmt.Printf("> ")
-						input := bufio.NewScanner(os.Stdin)
-						if input.Scan() {
-							config.FileName = strings.Trim(input.Text(), "\"")
-							log.Printf("Filename changed to: \"%s\"", config.FileName)
-						} else {
-							log.Println("Filename unchanged")
-						}
-						paused = false
-					case "3":
-						// Exit, send signal
-						signals <- os.Kill
-					default:
-						log.Printf("Invalid value: %s", choiceStr)
-						paused = false
+			select {
+			case sig := <-signals:
+				if sig == os.Interrupt && interactive {
+					if paused {
+						log.Fatal("Interrupt received twice. Force-closing.")
 					}
-				} else {
-					// `paused` is true, so os.Interrupt received twice
-					log.Fatal("Interrupt received twice. Force-closing.")
-					signals <- os.Kill
+					paused = true
+					go func() {
+						chosen <- pauseMenu(control)
+					}()
+					continue
+				}
+			case exit := <-chosen:
+				if paused = false; !exit {
+					continue
 				}
-			case os.Kill:
-				// Terminate now
-				done <- true
-				break
 			}
+			// Let requests in flight complete, then terminate
+			shutdown(server, *shutdownTimeout)
+			if tokenFile != "" {
+				os.Remove(tokenFile)
+			}
+			done <- true
+			return
 		}
-	}(&config)
+	}()
 
 	// Serve
 	go func() {
-		log.Printf("Starting server on port %s", *port)
-		if err := http.ListenAndServe(":"+*port, sm); err != nil {
+		address, scheme := listener.Addr().String(), "http"
+		if useTLS {
+			scheme = "https"
+		}
+		if listener.Addr().Network() == "unix" {
+			log.Printf("Starting server on unix:%s (%s)", address, scheme)
+		} else {
+			log.Printf("Starting server on %s://%s", scheme, address)
+		}
+		var err error
+		if useTLS {
+			err = server.ServeTLS(listener, certPath, keyPath)
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != http.ErrServerClosed {
 			log.Fatal(err)
 		}
 	}()
@@ -201,6 +438,369 @@ func main() {
 	// Done.
 }
 
+// pauseMenu offers to change settings at the terminal, and reports whether exiting was chosen instead.
+func pauseMenu(control *control) bool {
+	log.Println("Paused.")
+	fmt.Println("Choose one of the below options:")
+	fmt.Println("1. Toggle dark mode")
+	fmt.Println("2. Change filename")
+	fmt.Println("3. Exit")
+	fmt.Printf("> ")
+	var choiceStr string
+	fmt.Scanln(&choiceStr)
+	choiceStr = strings.TrimSpace(choiceStr)
+
+	switch choiceStr {
+	case "1":
+		// Toggle dark mode
+		status := "enabled"
+		if !control.ToggleDark().Dark {
+			status = "disabled"
+		}
+		log.Printf("Dark mode %s", status)
+	case "2":
+		// Change filename
+		fmt.Println("Enter in new Markdown filename: ")
+		fmt.Printf("> ")
+		input := bufio.NewScanner(os.Stdin)
+		if input.Scan() {
+			if s, err := control.SetFile(strings.Trim(input.Text(), "\"")); err != nil {
+				log.Println(err)
+			} else {
+				log.Printf("Filename changed to: \"%s\"", s.File)
+			}
+		} else {
+			log.Println("Filename unchanged")
+		}
+	case "3":
+		return true
+	default:
+		log.Printf("Invalid value: %s", choiceStr)
+	}
+	return false
+}
+
+// listen opens the listener for --bind and --port: a Unix socket for unix:PATH, and a TCP port otherwise. A socket
+// left behind by a server that is gone is replaced.
+func listen(bind, port string) (net.Listener, error) {
+	socket := strings.TrimPrefix(bind, "unix:")
+	if socket == bind {
+		return net.Listen("tcp", net.JoinHostPort(bind, port))
+	}
+	// The socket is removed on closing, by then from the root
+	socket, err := filepath.Abs(socket)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another server", socket)
+		}
+		os.Remove(socket)
+	}
+	return net.Listen("unix", socket)
+}
+
+// certificateHosts returns the names a self-signed certificate is made for: the local host, and the bind address
+// or, when listening on every interface, the host name.
+func certificateHosts(bind string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	ip := net.ParseIP(bind)
+	switch {
+	case bind == "" || ip != nil && ip.IsUnspecified():
+		if name, err := os.Hostname(); err == nil {
+			hosts = append(hosts, name)
+		}
+	case !strings.HasPrefix(bind, "unix:") && bind != "localhost" && (ip == nil || !ip.IsLoopback()):
+		hosts = append(hosts, bind)
+	}
+	return hosts
+}
+
+// shutdown stops server from accepting connections and waits up to timeout for requests in flight to complete, then
+// closes the connections still open.
+func shutdown(server *http.Server, timeout time.Duration) {
+	log.Println("Shutting down.")
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Requests still in flight after %s: %v", timeout, err)
+		server.Close()
+	}
+}
+
+// Markdown extensions that can be turned on or off, and those enabled by default
+var (
+	extensionNames = []string{"tables", "strikethrough", "tasklists", "linkify", "footnotes", "definitionlists",
+		"typographer", "emoji", "cjk", "highlighting", "diagrams", "wikilinks"}
+	defaultExtensions = []string{"gfm", "footnotes", "highlighting", "diagrams", "wikilinks"}
+)
+
+// Names standing for several extensions
+var extensionGroups = map[string][]string{"gfm": {"tables", "strikethrough", "tasklists", "linkify"}}
+
+// Extensions that need no settings of their own
+var extenders = map[string]goldmark.Extender{
+	"tables":          extension.Table,
+	"strikethrough":   extension.Strikethrough,
+	"tasklists":       extension.TaskList,
+	"linkify":         extension.Linkify,
+	"footnotes":       extension.Footnote,
+	"definitionlists": extension.DefinitionList,
+	"typographer":     extension.Typographer,
+	"emoji":           emoji.Emoji,
+	"cjk":             extension.CJK,
+}
+
+// markdownOptions selects what the Goldmark instances support: each of extensionNames, math, and raw HTML in
+// documents. Highlight maps every built-in theme name to its highlighting style.
+type markdownOptions struct {
+	Math       bool              `json:"math"`
+	HTML       bool              `json:"html"`
+	Extensions map[string]bool   `json:"extensions"`
+	Highlight  map[string]string `json:"highlight"`
+}
+
+// newMarkdownOptions checks the comma-separated lists of extension names and of theme:style pairs overriding the
+// highlighting styles of built-in themes.
+func newMarkdownOptions(math, html bool, extensions, highlight string) (markdownOptions, error) {
+	opts := markdownOptions{Math: math, HTML: html, Extensions: make(map[string]bool),
+		Highlight: make(map[string]string)}
+	for _, name := range extensionNames {
+		opts.Extensions[name] = false
+	}
+	for _, name := range strings.Split(extensions, ",") {
+		name = strings.TrimSpace(name)
+		names, ok := extensionGroups[name]
+		if !ok && name != "" {
+			names = []string{name}
+		}
+		for _, name := range names {
+			if _, known := opts.Extensions[name]; !known {
+				return opts, fmt.Errorf("unknown extension %q, expected some of gfm, %s", name,
+					strings.Join(extensionNames, ", "))
+			}
+			opts.Extensions[name] = true
+		}
+	}
+	for _, t := range builtinThemes {
+		opts.Highlight[t.name] = t.highlightStyle
+	}
+	styles, err := options.SplitPairs(highlight)
+	if err != nil {
+		return opts, fmt.Errorf("highlight: %v", err)
+	}
+	for name, style := range styles {
+		if _, known := opts.Highlight[name]; !known {
+			return opts, fmt.Errorf("highlight: unknown theme %q", name)
+		}
+		opts.Highlight[name] = style
+	}
+	return opts, nil
+}
+
+// markdownFlags defines the flags choosing how Markdown is rendered on fs, for the server and the other modes alike,
+// and returns a function checking the options they select.
+func markdownFlags(fs *flag.FlagSet) func() (markdownOptions, error) {
+	mathMode := fs.Bool("math", true, "enable math rendering")
+	highlight := fs.String("highlight", "", "highlighting styles by theme")
+	enabled := fs.String("extensions", strings.Join(defaultExtensions, ","), "enabled Markdown extensions")
+	rawHTML := fs.Bool("html", true, "render raw HTML in documents")
+	return func() (markdownOptions, error) {
+		return newMarkdownOptions(*mathMode, *rawHTML, *enabled, *highlight)
+	}
+}
+
+// defaultMarkdown returns the options enabling the default extensions and raw HTML.
+func defaultMarkdown(math bool) markdownOptions {
+	opts, _ := newMarkdownOptions(math, true, strings.Join(defaultExtensions, ","), "")
+	return opts
+}
+
+// goldmarkInitializer will initialize Goldmark with:
+// - The extensions enabled in opts, among them GitHub Flavored Markdown
+// - Syntax highlighting in the given style
+// - Server-side math rendering
+// - Server-side diagrams for dot and mermaid code blocks
+// - Wiki links resolved by notes
+// - Appropriate styling for given theme
+// - Custom HTML, if enabled in opts
+// - Auto heading ID generation
+func goldmarkInitializer(style string, dark bool, opts markdownOptions, notes wikilink.Resolver) goldmark.Markdown {
+	palette := diagram.Light
+	if dark {
+		palette = diagram.Dark
+	}
+	var extensions []goldmark.Extender
+	for _, name := range extensionNames {
+		if e, ok := extenders[name]; ok && opts.Extensions[name] {
+			extensions = append(extensions, e)
+		}
+	}
+	if opts.Extensions["highlighting"] {
+		extensions = append(extensions, highlighting.NewHighlighting(highlighting.WithStyle(style)))
+	}
+	if opts.Math {
+		extensions = append(extensions, mathml.Math)
+	}
+	if opts.Extensions["diagrams"] {
+		extensions = append(extensions, diagram.New(palette))
+	}
+	if opts.Extensions["wikilinks"] {
+		extensions = append(extensions, wikilink.New(notes))
+	}
+	var rendererOptions []renderer.Option
+	if opts.HTML {
+		rendererOptions = append(rendererOptions, html.WithUnsafe())
+	}
+	return goldmark.New(goldmark.WithExtensions(extensions...), goldmark.WithRendererOptions(rendererOptions...),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID(), parser.WithHeadingAttribute()))
+}
+
+// Built-in themes: the base stylesheet and the highlighting style matching it
+var builtinThemes = []struct {
+	name           string
+	dark           bool
+	highlightStyle string
+}{
+	{"dark", true, "solarized-dark"},
+	{"light", false, "monokailight"},
+	{"monokai", true, "monokai"},
+	{"dracula", true, "dracula"},
+	{"github", false, "github"},
+	{"solarized-light", false, "solarized-light"},
+}
+
+// newThemes builds the theme registry, with a Goldmark instance for each theme. A non-nil css replaces the embedded
+// stylesheets of all themes.
+func newThemes(opts markdownOptions, css []byte, notes wikilink.Resolver) *theme.Registry {
+	themes := theme.NewRegistry()
+	for _, t := range builtinThemes {
+		style := stylesheet(t.dark)
+		if css != nil {
+			style = template.CSS(css)
+		}
+		highlightStyle := opts.Highlight[t.name]
+		themes.Add(&theme.Theme{Name: t.name, Dark: t.dark, Style: style, HighlightStyle: highlightStyle,
+			Markdown: goldmarkInitializer(highlightStyle, t.dark, opts, notes)})
+	}
+	return themes
+}
+
+// newIndex creates the index of the Markdown files below dir that root would serve, so that nothing it refuses shows
+// up in search results, backlinks or the sidebar.
+func newIndex(dir string, root *resolve.Root) *search.Index {
+	gm := goldmark.New(goldmark.WithExtensions(extension.GFM, wikilink.New(nil)))
+	return search.New(dir, func(fileName string, source []byte) search.Content {
+		return Extract(gm, fileName, source)
+	}, func(p string) bool {
+		_, err := root.Resolve(p)
+		return err == nil
+	})
+}
+
+// serveFiles serves the files below root, rendering Markdown files with the settings in store.
+func serveFiles(store *settings.Store, root *resolve.Root, themes *theme.Registry, templ *template.Template,
+	pages *cache.Cache, index *search.Index) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s", r.Method, r.URL.Path)
+
+		// Each request renders from its own copy of the settings
+		config := store.Snapshot()
+
+		// Get pathname, confined to the root
+		requested := r.URL.Path
+		if requested == "/" {
+			requested = filepath.ToSlash(config.FileName)
+		}
+		fileName, err := root.Resolve(requested)
+		if err != nil {
+			refuse(w, r, requested, err)
+			return
+		}
+		config.FileName = fileName
+
+		t := selectTheme(themes, w, r, &config)
+		Render(w, r, t.Markdown, templ, config, pages, index, root.Resolve)
+	}
+}
+
+// selectTheme picks the theme chosen by the browser, or the default one, and applies it to config.
+func selectTheme(themes *theme.Registry, w http.ResponseWriter, r *http.Request, config *Config) *theme.Theme {
+	fallback := config.Theme
+	if fallback == "" && config.DarkMode {
+		fallback = "dark"
+	} else if fallback == "" {
+		fallback = "light"
+	}
+	t := themes.Select(w, r, fallback)
+	config.DarkMode = t.Dark
+	config.Style = t.Style
+	config.Theme = t.Name
+	return t
+}
+
+// stylesheet returns the embedded stylesheet for the given theme.
+func stylesheet(dark bool) template.CSS {
+	if dark {
+		return template.CSS(MustAsset("assets/dark.out.css"))
+	}
+	return template.CSS(MustAsset("assets/light.out.css"))
+}
+
+// loadStyle reads the user stylesheet at fileName. It returns nil if no file is given, so the embedded stylesheets
+// are used.
+func loadStyle(fileName string) ([]byte, error) {
+	if fileName == "" {
+		return nil, nil
+	}
+	css, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read stylesheet: %v", err)
+	}
+	return css, nil
+}
+
+// loadTemplate parses the user page template at fileName, falling back to the embedded one if no file is given. User
+// templates are checked against the fields of RenderedHTML.
+func loadTemplate(fileName string) (*template.Template, error) {
+	if fileName == "" {
+		return template.New("md").Parse(string(MustAsset("assets/index.gohtml")))
+	}
+	templateBytes, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read template: %v", err)
+	}
+	templ, err := template.New(filepath.Base(fileName)).Parse(string(templateBytes))
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateTemplate(templ); err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return templ, nil
+}
+
+// refuse reports why the requested path cannot be served.
+func refuse(w http.ResponseWriter, r *http.Request, requested string, err error) {
+	var msg string
+	switch err {
+	case resolve.ErrOutside, resolve.ErrInvalid:
+		log.Printf("Malicious access: %s", r.URL)
+		w.WriteHeader(http.StatusBadRequest)
+		msg = fmt.Sprintf("Bad Request: \"%s\": %v", requested, err)
+	case resolve.ErrForbidden:
+		w.WriteHeader(http.StatusForbidden)
+		msg = fmt.Sprintf("Forbidden: \"%s\": %v", requested, err)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		msg = fmt.Sprintf("404: \"%s\" cannot be opened", requested)
+	}
+	fmt.Fprintln(w, msg)
+	log.Println(msg)
+}
+
 // usage displays the appropriate notice if the user did not specify the Markdown file to render.
 func usage(note string) {
 	if len(note) > 0 {
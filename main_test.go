@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dienakakim/mds/lib/cache"
+	. "github.com/dienakakim/mds/lib/render"
+	"github.com/dienakakim/mds/lib/resolve"
+	"github.com/dienakakim/mds/lib/settings"
+	. "github.com/dienakakim/mds/lib/structs"
+)
+
+func TestDefaultTemplate(t *testing.T) {
+	templ, err := loadTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateTemplate(templ); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestConcurrentRequests renders different files in different themes at once while the settings change, as under
+// go test -race, and checks that every response shows the file it asked for.
+func TestConcurrentRequests(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	dir := t.TempDir()
+	names := []string{"a", "b", "c", "d", "e"}
+	for _, name := range names {
+		content := fmt.Sprintf("# Page %s\n\nmarker-%s\n", name, name)
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	root, err := resolve.New(".", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templ, err := loadTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := newIndex(".", root)
+	themes := newThemes(defaultMarkdown(false), nil, index)
+	store := settings.New(Config{FileName: "a.md", Style: stylesheet(false), Search: true, Nav: true})
+	control := newControl(store, root, themes, wd)
+	handler := serveFiles(store, root, themes, templ, cache.New(1<<20), index)
+
+	done := make(chan bool)
+	var changes sync.WaitGroup
+	changes.Add(1)
+	go func() {
+		defer changes.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			control.ToggleDark()
+			if _, err := control.SetFile(names[i%len(names)] + ".md"); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	// Workers take turns at the requests, as every page holds a whole stylesheet
+	themeNames := themes.Names()
+	jobs := make(chan int)
+	var requests sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+			for i := range jobs {
+				request(t, handler, names, names[i%len(names)], themeNames[i%len(themeNames)], i%4 == 0)
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	requests.Wait()
+	close(done)
+	changes.Wait()
+}
+
+// request asks handler for the file name in theme, or for the default file, and checks that the page shows that
+// file and only that one.
+func request(t *testing.T, handler http.HandlerFunc, names []string, name, theme string, byDefault bool) {
+	path := "/" + name + ".md"
+	if byDefault {
+		// Whichever file is the default at the time
+		path, name = "/", ""
+	}
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, path+"?theme="+theme, nil))
+	body := w.Body.String()
+	if w.Code != http.StatusOK {
+		t.Errorf("%s: status %d", path, w.Code)
+		return
+	}
+	if !strings.Contains(w.Header().Get("Set-Cookie"), "mds_theme="+theme) {
+		t.Errorf("%s: theme %s not selected", path, theme)
+	}
+	shown := 0
+	for _, other := range names {
+		if strings.Contains(body, "marker-"+other) {
+			shown++
+			if name != "" && other != name {
+				t.Errorf("%s: shows the content of %s.md", path, other)
+			}
+		}
+	}
+	if shown != 1 {
+		t.Errorf("%s: shows the content of %d files", path, shown)
+	}
+}
+
+// TestRootDirectory serves / without a default file, as when --file is not given.
+func TestRootDirectory(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "notes.md"), []byte("# Notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	root, err := resolve.New(".", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templ, err := loadTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := newIndex(".", root)
+	themes := newThemes(defaultMarkdown(false), nil, index)
+	store := settings.New(Config{FileName: ".", Style: stylesheet(false)})
+	handler := serveFiles(store, root, themes, templ, cache.New(1<<20), index)
+	get := func() string {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		return w.Body.String()
+	}
+
+	if body := get(); !strings.Contains(body, "Index of /") || !strings.Contains(body, "notes.md") {
+		t.Error("root not listed")
+	}
+	if err := ioutil.WriteFile("README.md", []byte("readme-content\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if body := get(); !strings.Contains(body, "readme-content") {
+		t.Error("README.md not shown")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/dienakakim/mds/lib/options"
+	. "github.com/dienakakim/mds/lib/render"
+	"github.com/dienakakim/mds/lib/resolve"
+	. "github.com/dienakakim/mds/lib/structs"
+)
+
+// printMain is the driver code for the print mode, which turns a single document into a PDF file, or into a page
+// meant for printing if the output file ends in .html. Both always use the light theme.
+func printMain(args []string) {
+	fs := flag.NewFlagSet("print", flag.ExitOnError)
+	markdownSettings := markdownFlags(fs)
+	file := fs.String("file", "index.md", "filename")
+	out := fs.String("out", "", "output file")
+	templateFile := fs.String("template", "", "page template file")
+	cssFile := fs.String("css", "", "stylesheet file")
+
+	// Markdown is rendered as by the server, with the settings of the same configuration files and environment
+	if _, err := options.LoadKnown(fs, ".", args); err != nil {
+		log.Fatal(err)
+	}
+	markdown, err := markdownSettings()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(*file, ".md") + ".pdf"
+	}
+
+	content, err := ioutil.ReadFile(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Wiki links are resolved against the current directory, as the server would with its root
+	root, err := resolve.New(".", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	index := newIndex(".", root)
+	if err := index.Refresh(); err != nil {
+		log.Fatal(err)
+	}
+	config := Config{FileName: *file, Math: markdown.Math, Style: stylesheet(false), Theme: "light"}
+	gm := goldmarkInitializer(markdown.Highlight["light"], false, markdown, index)
+
+	var output []byte
+	if strings.HasSuffix(*out, ".html") {
+		templ, err := loadTemplate(*templateFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		css, err := loadStyle(*cssFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if css != nil {
+			config.Style = template.CSS(css)
+		}
+		var page bytes.Buffer
+		if err := templ.Execute(&page, BuildPrint(gm, content, config)); err != nil {
+			log.Fatal(err)
+		}
+		output = page.Bytes()
+	} else if output, err = BuildPDF(gm, content, config, root.Resolve); err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*out, output, 0644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Printed \"%s\" to \"%s\"", *file, *out)
+}